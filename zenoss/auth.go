@@ -0,0 +1,248 @@
+package zenoss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// headerAPIKey is the header carrying the API key used by Zenoss Cloud and newer collection zones
+	headerAPIKey = "z-api-key"
+
+	// cookieSession is the cookie holding the session after logging in using the login form
+	cookieSession = "__ac"
+
+	pathLogin = "/zport/acl_users/cookieAuthHelper/login"
+)
+
+// Authenticator adds credentials to the requests made to Zenoss
+type Authenticator interface {
+	// Authenticate adds credentials to the request, the request context may be used when retrieving credentials
+	Authenticate(req *http.Request) error
+}
+
+// CredentialFunc returns a credential when a request is made, allowing credentials to be rotated
+// without rebuilding the client
+type CredentialFunc func(ctx context.Context) (string, error)
+
+// StaticCredential returns a credential which never changes
+func StaticCredential(value string) CredentialFunc {
+	return func(context.Context) (string, error) {
+		return value, nil
+	}
+}
+
+// FileCredential returns a credential read from the given file, e.g. a mounted secret. The file is read
+// again whenever its modification time changes. Leading and trailing whitespace is removed.
+func FileCredential(path string) CredentialFunc {
+	var (
+		mu      sync.Mutex
+		value   string
+		modTime time.Time
+	)
+	return func(context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		fi, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("unable to read credential: %w", err)
+		}
+		if fi.ModTime().Equal(modTime) {
+			return value, nil
+		}
+
+		b, err := os.ReadFile(path) //#nosec G304
+		if err != nil {
+			return "", fmt.Errorf("unable to read credential: %w", err)
+		}
+		value = strings.TrimSpace(string(b))
+		modTime = fi.ModTime()
+		return value, nil
+	}
+}
+
+type basicAuth struct {
+	username CredentialFunc
+	password CredentialFunc
+}
+
+// NewBasicAuth returns an authenticator sending username and password as HTTP basic authentication
+func NewBasicAuth(username, password CredentialFunc) Authenticator {
+	return &basicAuth{username: username, password: password}
+}
+
+func (a *basicAuth) Authenticate(req *http.Request) error {
+	username, err := a.username(req.Context())
+	if err != nil {
+		return err
+	}
+	password, err := a.password(req.Context())
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(username, password)
+	return nil
+}
+
+type headerAuth struct {
+	header string
+	value  CredentialFunc
+}
+
+// NewAPIKeyAuth returns an authenticator sending the given key in the z-api-key header as used by Zenoss Cloud
+func NewAPIKeyAuth(key CredentialFunc) Authenticator {
+	return NewHeaderAuth(headerAPIKey, key)
+}
+
+// NewHeaderAuth returns an authenticator setting the given header, e.g. for a token required by a proxy
+func NewHeaderAuth(header string, value CredentialFunc) Authenticator {
+	return &headerAuth{header: header, value: value}
+}
+
+func (a *headerAuth) Authenticate(req *http.Request) error {
+	v, err := a.value(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set(a.header, v)
+	return nil
+}
+
+// binder is implemented by authenticators which need the client to perform requests of their own
+type binder interface {
+	bind(c *client)
+}
+
+// sessionExpirer is implemented by authenticators holding a session which may expire
+type sessionExpirer interface {
+	// expire discards the session used by the request unless it has already been renewed
+	expire(req *http.Request)
+}
+
+type sessionAuth struct {
+	username CredentialFunc
+	password CredentialFunc
+
+	mu      sync.Mutex
+	jar     http.CookieJar
+	client  *http.Client
+	baseURL *url.URL
+}
+
+// NewSessionAuth returns an authenticator logging in once using the Zenoss login form and sending the
+// session cookie on following requests. The session is renewed when Zenoss redirects to the login page.
+// It must be given to New using WithAuthenticator as it performs the login using the client.
+func NewSessionAuth(username, password CredentialFunc) Authenticator {
+	return &sessionAuth{username: username, password: password}
+}
+
+func (a *sessionAuth) bind(c *client) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.baseURL, _ = url.Parse(c.url)
+	a.client = &http.Client{
+		Transport: c.client.Transport,
+		Timeout:   c.client.Timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	a.resetJar()
+}
+
+func (a *sessionAuth) Authenticate(req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.client == nil {
+		return fmt.Errorf("session authenticator is not bound to a client")
+	}
+
+	c := a.session()
+	if c == nil {
+		if err := a.login(req.Context()); err != nil {
+			return err
+		}
+		if c = a.session(); c == nil {
+			return fmt.Errorf("login to Zenoss returned no session: %w", ErrUnauthorized)
+		}
+	}
+	req.AddCookie(c)
+	return nil
+}
+
+func (a *sessionAuth) expire(req *http.Request) {
+	used, err := req.Cookie(cookieSession)
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if c := a.session(); c != nil && c.Value == used.Value {
+		a.resetJar()
+	}
+}
+
+// login posts the credentials to the login form storing the session cookie in the jar
+func (a *sessionAuth) login(ctx context.Context) error {
+	username, err := a.username(ctx)
+	if err != nil {
+		return err
+	}
+	password, err := a.password(ctx)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{
+		"__ac_name":     {username},
+		"__ac_password": {password},
+		"came_from":     {a.baseURL.String() + "/zport/dmd"},
+		"submitted":     {"true"},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL.String()+pathLogin, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("unable to create login request for Zenoss: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error logging in to Zenoss: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return &HTTPError{
+			StatusCode:  res.StatusCode,
+			ContentType: res.Header.Get("Content-Type"),
+			Err:         classify(res.StatusCode, ""),
+		}
+	}
+	return nil
+}
+
+// session returns the current session cookie or nil if not logged in
+func (a *sessionAuth) session() *http.Cookie {
+	for _, c := range a.jar.Cookies(a.baseURL) {
+		if c.Name == cookieSession && c.Value != "" {
+			return c
+		}
+	}
+	return nil
+}
+
+func (a *sessionAuth) resetJar() {
+	a.jar, _ = cookiejar.New(nil)
+	a.client.Jar = a.jar
+}
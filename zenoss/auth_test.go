@@ -0,0 +1,148 @@
+package zenoss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "secret", req.Header.Get("z-api-key"))
+		_, _, ok := req.BasicAuth()
+		assert.False(t, ok)
+		rw.Write([]byte(setInfoDeviceResponse))
+	}))
+	defer server.Close()
+	api.(*client).auth = NewAPIKeyAuth(StaticCredential("secret"))
+
+	err := api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300)
+	assert.NoError(t, err)
+}
+
+func TestRotatedCredential(t *testing.T) {
+	keys := []string{}
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		keys = append(keys, req.Header.Get("X-Token"))
+		rw.Write([]byte(setInfoDeviceResponse))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(path, []byte("first\n"), 0600))
+	api.(*client).auth = NewHeaderAuth("X-Token", FileCredential(path))
+
+	assert.NoError(t, api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300))
+	assert.NoError(t, os.WriteFile(path, []byte("second\n"), 0600))
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	assert.NoError(t, api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300))
+
+	assert.Equal(t, []string{"first", "second"}, keys)
+}
+
+func TestCredentialError(t *testing.T) {
+	calls := 0
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+	api.(*client).auth = NewBasicAuth(StaticCredential("user"), func(context.Context) (string, error) {
+		return "", errors.New("vault sealed")
+	})
+
+	err := api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300)
+	assert.ErrorContains(t, err, "vault sealed")
+	assert.Equal(t, 0, calls)
+}
+
+func TestSessionAuth(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		logins  int
+		session string
+	)
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch req.URL.Path {
+		case "/zport/acl_users/cookieAuthHelper/login":
+			assert.NoError(t, req.ParseForm())
+			assert.Equal(t, "user", req.PostForm.Get("__ac_name"))
+			assert.Equal(t, "pass", req.PostForm.Get("__ac_password"))
+			logins++
+			session = fmt.Sprintf("session%d", logins)
+			http.SetCookie(rw, &http.Cookie{Name: "__ac", Value: session, Path: "/"})
+			http.Redirect(rw, req, req.PostForm.Get("came_from"), http.StatusFound)
+		case "/zport/acl_users/cookieAuthHelper/login_form":
+			rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+			rw.Write([]byte("<html><body>Login</body></html>"))
+		default:
+			_, _, basic := req.BasicAuth()
+			assert.False(t, basic)
+			c, err := req.Cookie("__ac")
+			if err != nil || c.Value != session {
+				http.Redirect(rw, req, "/zport/acl_users/cookieAuthHelper/login_form", http.StatusFound)
+				return
+			}
+			rw.Write([]byte(setInfoDeviceResponse))
+		}
+	}))
+	defer server.Close()
+
+	c := api.(*client)
+	c.auth = NewSessionAuth(StaticCredential("user"), StaticCredential("pass"))
+	c.auth.(binder).bind(c)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, logins)
+
+	// Expire the session on the server
+	mu.Lock()
+	session = "expired"
+	mu.Unlock()
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 2, logins)
+}
+
+func TestSessionAuthLoginFailed(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/zport/acl_users/cookieAuthHelper/login" {
+			http.Redirect(rw, req, "/zport/acl_users/cookieAuthHelper/login_form?submitted=true", http.StatusFound)
+			return
+		}
+		t.Error("unexpected request", req.URL.Path)
+	}))
+	defer server.Close()
+
+	c := api.(*client)
+	c.auth = NewSessionAuth(StaticCredential("user"), StaticCredential("wrong"))
+	c.auth.(binder).bind(c)
+
+	err := api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
@@ -0,0 +1,173 @@
+package zenoss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Batch collects router calls which are sent as Ext.Direct batches, one HTTP POST per router,
+// instead of a round trip per call. Calls are added using the builder methods and sent by Do.
+type Batch struct {
+	ctx    context.Context
+	client *client
+	calls  []*batchCall
+}
+
+type batchCall struct {
+	path   string
+	req    request
+	target enveloped
+	check  func() error
+	err    error
+}
+
+// Batch starts a new batch of calls using the given context for all requests
+func (z *client) Batch(ctx context.Context) *Batch {
+	return &Batch{ctx: ctx, client: z}
+}
+
+// AddEvent adds creation of an event to the batch, see Client.AddEvent
+func (b *Batch) AddEvent(summary, message, device, component string, severity Severity, evClass, evKey string, extraData map[string]string) *Batch {
+	req, err := b.client.addEventRequest(summary, message, device, component, severity, evClass, evKey, extraData)
+	var resp addEventResponse
+	return b.add(pathEvconsoleRouter, req, &resp, err, func() error {
+		if !resp.Result.Success {
+			return fmt.Errorf("event could not be created: %w", resp.failure(resp.Result))
+		}
+		return nil
+	})
+}
+
+// UpdateDeviceProductionState adds an update of the production state of a device to the batch,
+// see Client.UpdateDeviceProductionState
+func (b *Batch) UpdateDeviceProductionState(uid string, state ProductionState) *Batch {
+	var res deviceSetInfoResponse
+	return b.add(pathDeviceRouter, deviceProductionStateRequest(uid, state), &res, nil, func() error {
+		if !res.Result.Success {
+			return fmt.Errorf("update device returned unsuccessful: %w", res.failure(res.Result))
+		}
+		return nil
+	})
+}
+
+// UpdateCustomProperty adds an update of a custom property to the batch, see Client.UpdateCustomProperty
+func (b *Batch) UpdateCustomProperty(uid string, id string, value string) *Batch {
+	var res customPropertyUpdateResponse
+	return b.add(pathPropertiesRouter, customPropertyUpdateRequest(uid, id, value), &res, nil, func() error {
+		if !res.Result.Success {
+			return fmt.Errorf("update custom property returned unsuccessful: %w", res.failure(res.Result))
+		}
+		return nil
+	})
+}
+
+// Len returns the number of calls added to the batch
+func (b *Batch) Len() int {
+	return len(b.calls)
+}
+
+// Do sends the calls of the batch. The returned slice holds the error, or nil, of each call in the
+// order they were added. The returned error joins all errors of the calls and is nil if all succeeded.
+func (b *Batch) Do() ([]error, error) {
+	var paths []string
+	groups := map[string][]*batchCall{}
+	for _, c := range b.calls {
+		if c.err != nil {
+			continue
+		}
+		if _, ok := groups[c.path]; !ok {
+			paths = append(paths, c.path)
+		}
+		groups[c.path] = append(groups[c.path], c)
+	}
+
+	for _, path := range paths {
+		b.client.sendBatch(b.ctx, path, groups[path])
+	}
+
+	errs := make([]error, len(b.calls))
+	for i, c := range b.calls {
+		errs[i] = c.err
+	}
+	return errs, errors.Join(errs...)
+}
+
+func (b *Batch) add(path string, req request, target enveloped, err error, check func() error) *Batch {
+	b.calls = append(b.calls, &batchCall{
+		path:   path,
+		req:    req,
+		target: target,
+		check:  check,
+		err:    err,
+	})
+	return b
+}
+
+// sendBatch sends the calls in a single request to the router and sets the outcome on each call
+func (z *client) sendBatch(ctx context.Context, routerPath string, calls []*batchCall) {
+	byTid := make(map[int]*batchCall, len(calls))
+	reqs := make([]request, len(calls))
+	idempotent := true
+	for i, c := range calls {
+		c.req.Tid = z.nextTid()
+		byTid[c.req.Tid] = c
+		reqs[i] = c.req
+		idempotent = idempotent && idempotentMethods[c.req.Method]
+	}
+
+	// A batch of one is sent as a plain request which all Ext.Direct routers understand
+	var payload interface{} = reqs
+	if len(reqs) == 1 {
+		payload = reqs[0]
+	}
+
+	status, body, err := z.send(ctx, routerPath, payload, idempotent)
+	if err != nil {
+		for _, c := range calls {
+			c.err = err
+		}
+		return
+	}
+
+	if len(reqs) == 1 {
+		calls[0].finish(decodeResponse(status, body, calls[0].target))
+		return
+	}
+
+	var raws []json.RawMessage
+	if err := json.Unmarshal(body, &raws); err != nil {
+		err = fmt.Errorf("unable to parse batch response from Zenoss: %w - response: %s", err, excerpt(body))
+		for _, c := range calls {
+			c.err = err
+		}
+		return
+	}
+
+	for _, raw := range raws {
+		var env response
+		if err := json.Unmarshal(raw, &env); err != nil {
+			continue
+		}
+		c, ok := byTid[env.Tid]
+		if !ok {
+			continue
+		}
+		delete(byTid, env.Tid)
+		c.finish(decodeResponse(status, raw, c.target))
+	}
+
+	for tid, c := range byTid {
+		c.err = fmt.Errorf("no response returned for %s.%s with tid %d", c.req.Action, c.req.Method, tid)
+	}
+}
+
+// finish records the outcome of the call given the error from decoding its response
+func (c *batchCall) finish(err error) {
+	if err != nil {
+		c.err = err
+		return
+	}
+	c.err = c.check()
+}
@@ -0,0 +1,66 @@
+package zenoss
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	posts := map[string]int{}
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		posts[req.URL.Path]++
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+
+		switch req.URL.Path {
+		case "/zport/dmd/evconsole_router":
+			assert.True(t, strings.HasPrefix(buf.String(), `[{"action":"EventsRouter","method":"add_event"`))
+			// Responses are deliberately returned out of order
+			rw.Write([]byte(`[
+				{"action": "EventsRouter", "method": "add_event", "tid": 2, "type": "rpc", "result": {"msg": "Event not created", "success": false}},
+				{"action": "EventsRouter", "method": "add_event", "tid": 1, "type": "rpc", "result": {"msg": "Created event", "success": true}}
+			]`))
+		case "/zport/dmd/properties_router":
+			assert.Equal(t, `{"action":"PropertiesRouter","method":"update","data":[{"uid":"/zport/dmd/Devices/device","id":"cValue","value":"15"}],"tid":3}`, buf.String())
+			rw.Write([]byte(updateCustomPropertyResponse))
+		}
+	}))
+	defer server.Close()
+
+	errs, err := api.Batch(context.Background()).
+		AddEvent("summary", "message", "device", "component", SeverityCritical, "/Prometheus", "key1", nil).
+		UpdateCustomProperty("/zport/dmd/Devices/device", "cValue", "15").
+		AddEvent("summary", "message", "device", "component", SeverityCritical, "/Prometheus", "key2", nil).
+		AddEvent(strings.Repeat("s", 256), "message", "device", "component", SeverityCritical, "/Prometheus", "key3", nil).
+		Do()
+
+	assert.Error(t, err)
+	assert.Len(t, errs, 4)
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.ErrorContains(t, errs[2], "Event not created")
+	assert.ErrorContains(t, errs[3], "'summary' must be less than 255")
+	assert.Equal(t, 1, posts["/zport/dmd/evconsole_router"])
+	assert.Equal(t, 1, posts["/zport/dmd/properties_router"])
+}
+
+func TestBatchMissingResponse(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`[{"action": "DeviceRouter", "method": "setInfo", "tid": 1, "type": "rpc", "result": {"success": true}}]`))
+	}))
+	defer server.Close()
+
+	errs, err := api.Batch(context.Background()).
+		UpdateDeviceProductionState("/zport/dmd/Devices/a", 300).
+		UpdateDeviceProductionState("/zport/dmd/Devices/b", 300).
+		Do()
+
+	assert.Error(t, err)
+	assert.NoError(t, errs[0])
+	assert.ErrorContains(t, errs[1], "no response returned")
+}
@@ -0,0 +1,93 @@
+package zenoss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// routerPaths maps the Ext.Direct actions of the routers to the path they are served at below /zport/dmd
+// where it does not follow the naming convention, e.g. DeviceRouter at device_router
+var routerPaths = map[string]string{
+	"EventsRouter":           "evconsole_router",
+	"EventClassesRouter":     "evclasses_router",
+	"DetailNavRouter":        "detailnav_router",
+	"DeviceManagementRouter": "devicemanagement_router",
+	"ZenPackRouter":          "zenpack_router",
+}
+
+// routerPath returns the path of the router serving the given Ext.Direct action
+func routerPath(router string) string {
+	if p, ok := routerPaths[router]; ok {
+		return p
+	}
+
+	var b strings.Builder
+	for i, r := range router {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+type callResponse struct {
+	response
+	Result json.RawMessage `json:"result"`
+}
+
+// Call calls any method of a Zenoss router, e.g. Call(ctx, "DeviceRouter", "getInfo", data, &out).
+// The data is sent as the single argument of the method and the result of the call is decoded into out
+// unless it is nil. Results reporting success as false are returned as *APIError.
+func (z *client) Call(ctx context.Context, router, name string, data interface{}, out interface{}) error {
+	return z.CallPath(ctx, router, routerPath(router), name, data, out)
+}
+
+// CallPath calls any method of a Zenoss router like Call but with the router served at the given path below /zport/dmd,
+// e.g. CallPath(ctx, "EventClassesRouter", "evclasses_router", "getTree", data, &out), for routers not known by routerPath
+func (z *client) CallPath(ctx context.Context, router, path, name string, data interface{}, out interface{}) error {
+	if data == nil {
+		data = struct{}{}
+	}
+	req := request{
+		Action: action(router),
+		Method: method(name),
+		Data:   []interface{}{data},
+	}
+	var res callResponse
+	err := z.doRequest(ctx, req, strings.Trim(path, "/"), &res)
+	if err != nil {
+		return fmt.Errorf("unable to call %s.%s: %w", router, name, err)
+	}
+
+	// Not all methods return an object, e.g. getTree returns a list of nodes
+	var status struct {
+		Success *bool  `json:"success"`
+		Msg     string `json:"msg"`
+	}
+	if json.Unmarshal(res.Result, &status) == nil && status.Success != nil && !*status.Success {
+		return fmt.Errorf("%s.%s returned unsuccessful: %w", router, name, res.failure(result{Msg: status.Msg}))
+	}
+
+	if out == nil {
+		return nil
+	}
+	err = json.Unmarshal(res.Result, out)
+	if err != nil {
+		return fmt.Errorf("unable to parse result of %s.%s: %w - result: %s", router, name, err, excerpt(res.Result))
+	}
+	return nil
+}
+
+// CallAs calls any method of a Zenoss router like Client.Call returning the result decoded as T
+func CallAs[T any](ctx context.Context, c Client, router, name string, data interface{}) (T, error) {
+	var result T
+	err := c.Call(ctx, router, name, data, &result)
+	return result, err
+}
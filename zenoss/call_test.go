@@ -0,0 +1,71 @@
+package zenoss
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCall(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/zport/dmd/template_router", req.URL.Path)
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"TemplateRouter","method":"getTemplates","data":[{"id":"/zport/dmd/Devices"}],"tid":1}`, buf.String())
+		rw.Write([]byte(`{"uuid": "1", "action": "TemplateRouter", "result": [{"uid": "/zport/dmd/Devices/rrdTemplates/Device", "text": "Device"}], "tid": 1, "type": "rpc", "method": "getTemplates"}`))
+	}))
+	defer server.Close()
+
+	type template struct {
+		UID  string `json:"uid"`
+		Text string `json:"text"`
+	}
+	templates, err := CallAs[[]template](context.Background(), api, "TemplateRouter", "getTemplates", map[string]string{"id": "/zport/dmd/Devices"})
+	assert.NoError(t, err)
+	assert.Equal(t, []template{{UID: "/zport/dmd/Devices/rrdTemplates/Device", Text: "Device"}}, templates)
+}
+
+func TestCallUnsuccessful(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/zport/dmd/evconsole_router", req.URL.Path)
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"EventsRouter","method":"acknowledge","data":[{}],"tid":1}`, buf.String())
+		rw.Write([]byte(`{"uuid": "1", "action": "EventsRouter", "result": {"msg": "Permission denied", "success": false}, "tid": 1, "type": "rpc", "method": "acknowledge"}`))
+	}))
+	defer server.Close()
+
+	err := api.Call(context.Background(), "EventsRouter", "acknowledge", nil, nil)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestRouterPath(t *testing.T) {
+	assert.Equal(t, "device_router", routerPath("DeviceRouter"))
+	assert.Equal(t, "jobs_router", routerPath("JobsRouter"))
+	assert.Equal(t, "evconsole_router", routerPath("EventsRouter"))
+	assert.Equal(t, "zenpack_router", routerPath("ZenPackRouter"))
+	assert.Equal(t, "evclasses_router", routerPath("EventClassesRouter"))
+	assert.Equal(t, "devicemanagement_router", routerPath("DeviceManagementRouter"))
+}
+
+func TestCallPath(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/zport/dmd/mibs_router", req.URL.Path)
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"MibRouter","method":"getTree","data":[{"id":"/zport/dmd/Mibs"}],"tid":1}`, buf.String())
+		rw.Write([]byte(`{"uuid": "1", "action": "MibRouter", "result": [{"uid": "/zport/dmd/Mibs"}], "tid": 1, "type": "rpc", "method": "getTree"}`))
+	}))
+	defer server.Close()
+
+	var nodes []OrganizerNode
+	err := api.CallPath(context.Background(), "MibRouter", "/mibs_router", "getTree", map[string]string{"id": "/zport/dmd/Mibs"}, &nodes)
+	assert.NoError(t, err)
+	if assert.Len(t, nodes, 1) {
+		assert.Equal(t, "/Mibs", nodes[0].Path)
+	}
+}
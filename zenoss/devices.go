@@ -0,0 +1,766 @@
+package zenoss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+)
+
+func (z *client) ListDevices(ctx context.Context, query DeviceQuery) (*DeviceList, error) {
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodGetDevices,
+		Data: []interface{}{
+			query.readData(),
+		},
+	}
+	var res deviceReadResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list devices: %w", err)
+	}
+
+	if !res.Result.Success {
+		return nil, fmt.Errorf("error listing devices: %w", res.failure(res.Result.result))
+	}
+
+	if res.Result.Count == 0 {
+		return &DeviceList{Devices: []Device{}, Hash: res.Result.Hash}, nil
+	}
+
+	return &DeviceList{
+		Devices:    res.Result.Devices,
+		TotalCount: res.Result.Count,
+		Hash:       res.Result.Hash,
+	}, nil
+}
+
+func (z *client) GetDeviceInfo(ctx context.Context, uid string, keys ...string) (*DeviceInfo, error) {
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodGetInfo,
+		Data: []interface{}{
+			deviceInfoData{
+				UID:  uid,
+				Keys: keys,
+			},
+		},
+	}
+	var res deviceInfoResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read device info: %w", err)
+	}
+
+	if !res.Result.Success {
+		return nil, fmt.Errorf("error reading device info: %w", res.failure(res.Result.result))
+	}
+
+	return &res.Result.Data, nil
+}
+
+func (z *client) UpdateDevice(ctx context.Context, uid string, update DeviceUpdate) error {
+	if update == (DeviceUpdate{}) {
+		return nil
+	}
+
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodSetInfo,
+		Data: []interface{}{
+			deviceSetInfoData{
+				UID:          uid,
+				DeviceUpdate: update,
+			},
+		},
+	}
+	var res deviceSetInfoResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return fmt.Errorf("unable to update device: %w", err)
+	}
+
+	if !res.Result.Success {
+		return fmt.Errorf("update device returned unsuccessful: %w", res.failure(res.Result))
+	}
+
+	return nil
+}
+
+// MoveResult is the result of moving devices to another device class
+type MoveResult struct {
+	// Job is the job moving the devices, or nil if Zenoss moved them without starting a job
+	Job *Job
+
+	// UIDs are the uids of the devices after the move in the same order as given to MoveDevices
+	UIDs []string
+
+	client Client
+}
+
+// Resolve waits for the move to finish and reads the moved devices
+func (r *MoveResult) Resolve(ctx context.Context) ([]Device, error) {
+	if r.Job != nil {
+		if _, err := r.Job.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("unable to move devices: %w", err)
+		}
+	}
+
+	devices := make([]Device, 0, len(r.UIDs))
+	for _, uid := range r.UIDs {
+		dev, err := r.client.ReadDevice(ctx, uid)
+		if err != nil {
+			return nil, err
+		}
+		if dev == nil {
+			return nil, fmt.Errorf("device %s not found after move: %w", uid, ErrNotFound)
+		}
+		devices = append(devices, *dev)
+	}
+	return devices, nil
+}
+
+func (z *client) MoveDevices(ctx context.Context, uids []string, targetClass string) (*MoveResult, error) {
+	if len(uids) == 0 {
+		return &MoveResult{UIDs: []string{}, client: z}, nil
+	}
+
+	target := deviceClassUID(targetClass)
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodMoveDevices,
+		Data: []interface{}{
+			deviceMoveData{
+				deviceSelection: deviceSelection{UIDs: uids},
+				Target:          target,
+			},
+		},
+	}
+	var res newJobsResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return nil, fmt.Errorf("unable to move devices: %w", err)
+	}
+
+	if !res.Result.Success {
+		return nil, fmt.Errorf("move devices returned unsuccessful: %w", res.failure(res.Result.result))
+	}
+
+	// Devices are stored below the devices relation of their class so the uid follows the class
+	moved := make([]string, len(uids))
+	for i, uid := range uids {
+		moved[i] = target + "/devices/" + path.Base(uid)
+	}
+	return &MoveResult{Job: z.newJob(res.Result.Jobs), UIDs: moved, client: z}, nil
+}
+
+// deviceClassUID returns the uid of a device class given by its path, e.g. /Server/Linux, or by its uid
+func deviceClassUID(class string) string {
+	return rootedOrganizerUID(OrganizerDeviceClasses, class)
+}
+
+func (z *client) RenameDevice(ctx context.Context, uid, newID string, retainGraphs bool) (string, error) {
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodRenameDevice,
+		Data: []interface{}{
+			deviceRenameData{
+				UID:          uid,
+				NewID:        newID,
+				RetainGraphs: retainGraphs,
+			},
+		},
+	}
+	var res deviceRenameResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return "", fmt.Errorf("unable to rename device: %w", err)
+	}
+
+	if !res.Result.Success {
+		return "", fmt.Errorf("rename device returned unsuccessful: %w", res.failure(res.Result.result))
+	}
+
+	if res.Result.UID == "" {
+		return path.Dir(uid) + "/" + newID, nil
+	}
+	return res.Result.UID, nil
+}
+
+func (z *client) ResetIP(ctx context.Context, uids []string, ip string) error {
+	if len(uids) == 0 {
+		return nil
+	}
+
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodResetIP,
+		Data: []interface{}{
+			deviceResetIPData{
+				deviceSelection: deviceSelection{UIDs: uids},
+				IP:              ip,
+			},
+		},
+	}
+	var res deviceBulkResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return fmt.Errorf("unable to reset IP: %w", err)
+	}
+
+	if !res.Result.Success {
+		return fmt.Errorf("reset IP returned unsuccessful: %w", res.failure(res.Result))
+	}
+
+	return nil
+}
+
+func (z *client) SetManageIP(ctx context.Context, uid, ip string) error {
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodSetManageIP,
+		Data: []interface{}{
+			deviceManageIPData{
+				UID: uid,
+				IP:  ip,
+			},
+		},
+	}
+	var res deviceBulkResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return fmt.Errorf("unable to set manage IP: %w", err)
+	}
+
+	if !res.Result.Success {
+		return fmt.Errorf("set manage IP returned unsuccessful: %w", res.failure(res.Result))
+	}
+
+	return nil
+}
+
+func (z *client) RemodelDevice(ctx context.Context, uid string) (*Job, error) {
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodRemodel,
+		Data: []interface{}{
+			deviceRemodelData{
+				DeviceUID: uid,
+			},
+		},
+	}
+	var res deviceRemodelResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return nil, fmt.Errorf("unable to remodel device: %w", err)
+	}
+
+	if !res.Result.Success {
+		return nil, fmt.Errorf("remodel device returned unsuccessful: %w", res.failure(res.Result.result))
+	}
+
+	if res.Result.JobID == "" {
+		return nil, fmt.Errorf("remodel device of %s returned no job", uid)
+	}
+	return z.newJob([]jobRef{{UUID: res.Result.JobID}}), nil
+}
+
+// ModelResult is the outcome of modeling a device
+type ModelResult struct {
+	// Job is the modeling job as reported by Zenoss when it finished
+	Job *JobInfo
+
+	// LastCollected is when the device was last modeled successfully
+	LastCollected Timestamp
+
+	// Log is the output of the modeling job
+	Log []string
+}
+
+func (z *client) WaitForModel(ctx context.Context, uid string, job *Job) (*ModelResult, error) {
+	if job == nil {
+		return nil, fmt.Errorf("unable to wait for modeling of %s: no job given", uid)
+	}
+
+	info, err := job.Wait(ctx)
+	if info == nil {
+		return nil, fmt.Errorf("unable to wait for modeling of %s: %w", uid, err)
+	}
+	res := &ModelResult{Job: info, Log: info.Log}
+	if err != nil {
+		return res, fmt.Errorf("modeling of %s failed: %w", uid, err)
+	}
+
+	dev, err := z.GetDeviceInfo(ctx, uid, "lastCollected")
+	if err != nil {
+		return res, fmt.Errorf("unable to read device after modeling: %w", err)
+	}
+	res.LastCollected = dev.LastCollected
+	return res, nil
+}
+
+func (z *client) LockDevices(ctx context.Context, uids []string, opts LockOptions) error {
+	if len(uids) == 0 {
+		return nil
+	}
+
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodLockDevices,
+		Data: []interface{}{
+			deviceLockData{
+				deviceSelection: deviceSelection{UIDs: uids},
+				Updates:         opts.Updates,
+				Deletion:        opts.Deletion,
+				SendEvent:       opts.SendEvent,
+			},
+		},
+	}
+	var res deviceBulkResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return fmt.Errorf("unable to lock devices: %w", err)
+	}
+
+	if !res.Result.Success {
+		return fmt.Errorf("lock devices returned unsuccessful: %w", res.failure(res.Result))
+	}
+
+	return nil
+}
+
+func (z *client) UnlockDevices(ctx context.Context, uids []string) error {
+	return z.LockDevices(ctx, uids, LockOptions{})
+}
+
+func (z *client) EnsureDevice(ctx context.Context, dev NewDevice) (*Device, error) {
+	organizers := []string{}
+	for _, p := range dev.GroupPaths {
+		organizers = append(organizers, rootedOrganizerUID(OrganizerGroups, p))
+	}
+	for _, p := range dev.SystemPaths {
+		organizers = append(organizers, rootedOrganizerUID(OrganizerSystems, p))
+	}
+	if dev.LocationPath != "" {
+		organizers = append(organizers, rootedOrganizerUID(OrganizerLocations, dev.LocationPath))
+	}
+	if dev.Class != "" {
+		organizers = append(organizers, deviceClassUID(dev.Class))
+	}
+	for _, o := range organizers {
+		if err := z.EnsureOrganizerPath(ctx, o); err != nil {
+			return nil, err
+		}
+	}
+
+	existing, err := z.devicesNamed(ctx, dev.Name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to check if device %s exists: %w", dev.Name, err)
+	}
+	switch len(existing) {
+	case 0:
+		return z.CreateDevice(ctx, dev)
+	case 1:
+		return &existing[0], nil
+	default:
+		return nil, fmt.Errorf("%w for device %s", ErrMultipleResults, dev.Name)
+	}
+}
+
+// maxNameMatches bounds the number of devices matching a name which devicesNamed reads looking for exact matches
+const maxNameMatches = 1000
+
+// devicesNamed returns all devices with exactly the given name. Zenoss matches the name as a pattern
+// so the matching devices are read page by page and those with other names, e.g. web10 for web1, are skipped.
+// Changes to the matching devices between pages are tolerated as only the exact matches are of interest.
+func (z *client) devicesNamed(ctx context.Context, name string) ([]Device, error) {
+	var devices []Device
+	seen := map[string]bool{}
+	query := DeviceQuery{Name: name, Limit: defaultPageSize}
+	for {
+		list, err := z.ListDevices(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range list.Devices {
+			if d.Name == name && !seen[d.UID] {
+				seen[d.UID] = true
+				devices = append(devices, d)
+			}
+		}
+
+		query.Start += len(list.Devices)
+		if len(list.Devices) == 0 || query.Start >= list.TotalCount {
+			return devices, nil
+		}
+		if query.Start >= maxNameMatches {
+			if len(devices) > 0 {
+				return devices, nil
+			}
+			return nil, fmt.Errorf("more than %d devices match name %s", maxNameMatches, name)
+		}
+	}
+}
+
+func (z *client) DeleteDevices(ctx context.Context, uids []string, opts DeleteOptions) error {
+	if len(uids) == 0 {
+		return nil
+	}
+
+	mode, organizer := opts.Mode, ""
+	switch mode {
+	case "":
+		mode = DeleteModeDelete
+	case DeleteModeDelete:
+	case DeleteModeRemoveFromOrganizer:
+		if opts.Organizer == "" {
+			return fmt.Errorf("an organizer is required to remove devices from")
+		}
+		organizer = organizerUID(opts.Organizer)
+	default:
+		return fmt.Errorf("unknown delete mode %q", mode)
+	}
+	return z.removeDevices(ctx, uids, mode, organizer, opts.DeleteEvents, &opts.DeletePerf)
+}
+
+// removeDevices deletes the devices or removes them from the organizer after checking that they all exist
+func (z *client) removeDevices(ctx context.Context, uids []string, mode DeleteMode, organizer string, deleteEvents bool, deletePerf *bool) error {
+	reads := make([]deviceReadResponse, len(uids))
+	b := z.Batch(ctx)
+	for i := range uids {
+		uid, read := uids[i], &reads[i]
+		req := request{
+			Action: actionDeviceRoute,
+			Method: methodGetDevices,
+			Data:   []interface{}{deviceReadData{UID: uid}},
+		}
+		b.add(pathDeviceRouter, req, read, nil, func() error {
+			if !read.Result.Success {
+				return fmt.Errorf("error reading device %s: %w", uid, read.failure(read.Result.result))
+			}
+			if read.Result.Count == 0 || len(read.Result.Devices) == 0 {
+				return fmt.Errorf("device %s: %w", uid, ErrNotFound)
+			}
+			return nil
+		})
+	}
+	if _, err := b.Do(); err != nil {
+		return fmt.Errorf("unable to read hash for deleting device: %w", err)
+	}
+
+	// The hash read with the first device is sent as the hashcheck, Zenoss only verifies it when devices
+	// are selected by ranges so the hashes of the other devices are not needed
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodRemoveDevices,
+		Data: []interface{}{
+			deviceRemoveData{
+				Action:       mode,
+				UIDs:         uids,
+				Hashcheck:    reads[0].Result.Hash,
+				UID:          organizer,
+				DeleteEvents: deleteEvents,
+				DeletePerf:   deletePerf,
+			},
+		},
+	}
+	var res deviceRemoveResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return fmt.Errorf("unable to delete device: %w", err)
+	}
+
+	if !res.Result.Success {
+		err = res.failure(res.Result)
+		if mode == DeleteModeDelete && !errors.Is(err, ErrLocked) {
+			// Zenoss does not always say why a device was not deleted so check if it is locked
+			for _, uid := range uids {
+				info, ierr := z.GetDeviceInfo(ctx, uid, "locking")
+				if ierr == nil && info.Locking != nil && (info.Locking.Deletion || info.Locking.Updates) {
+					return fmt.Errorf("device %s is locked from deletion: %w", uid, ErrLocked)
+				}
+			}
+		}
+		return fmt.Errorf("remove device returned unsuccessful: %w", err)
+	}
+
+	return nil
+}
+
+func (z *client) SetProductionState(ctx context.Context, uids []string, state ProductionState) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	return z.setProductionState(ctx, deviceSelection{UIDs: uids}, state)
+}
+
+func (z *client) SetProductionStateByQuery(ctx context.Context, query DeviceQuery, state ProductionState) error {
+	sel, err := z.selectDevices(ctx, query)
+	if err != nil || sel == nil {
+		return err
+	}
+	return z.setProductionState(ctx, *sel, state)
+}
+
+func (z *client) setProductionState(ctx context.Context, sel deviceSelection, state ProductionState) error {
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodSetProductionState,
+		Data: []interface{}{
+			deviceProductionStateData{
+				deviceSelection: sel,
+				ProdState:       state,
+			},
+		},
+	}
+	var res deviceBulkResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return fmt.Errorf("unable to set production state: %w", err)
+	}
+
+	if !res.Result.Success {
+		return fmt.Errorf("set production state returned unsuccessful: %w", res.failure(res.Result))
+	}
+
+	return nil
+}
+
+func (z *client) SetPriority(ctx context.Context, uids []string, priority int) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	return z.setPriority(ctx, deviceSelection{UIDs: uids}, priority)
+}
+
+func (z *client) SetPriorityByQuery(ctx context.Context, query DeviceQuery, priority int) error {
+	sel, err := z.selectDevices(ctx, query)
+	if err != nil || sel == nil {
+		return err
+	}
+	return z.setPriority(ctx, *sel, priority)
+}
+
+func (z *client) setPriority(ctx context.Context, sel deviceSelection, priority int) error {
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodSetPriority,
+		Data: []interface{}{
+			devicePriorityData{
+				deviceSelection: sel,
+				Priority:        priority,
+			},
+		},
+	}
+	var res deviceBulkResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return fmt.Errorf("unable to set priority: %w", err)
+	}
+
+	if !res.Result.Success {
+		return fmt.Errorf("set priority returned unsuccessful: %w", res.failure(res.Result))
+	}
+
+	return nil
+}
+
+// selectDevices returns a selection of the devices matching the query, or nil if no devices match.
+// Zenoss resolves the selection on the server and fails with ErrHashMismatch if the devices have
+// changed since the query was made.
+func (z *client) selectDevices(ctx context.Context, query DeviceQuery) (*deviceSelection, error) {
+	probe := query
+	probe.Start = 0
+	probe.Limit = 1
+	list, err := z.ListDevices(ctx, probe)
+	if err != nil {
+		return nil, err
+	}
+
+	start, stop := query.Start, list.TotalCount-1
+	if query.Limit > 0 {
+		stop = min(stop, query.Start+query.Limit-1)
+	}
+	if start > stop {
+		return nil, nil
+	}
+
+	data := query.readData()
+	return &deviceSelection{
+		UIDs:      []string{},
+		Hashcheck: list.Hash,
+		UID:       data.UID,
+		Ranges:    [][2]int{{start, stop}},
+		Params:    data.Params,
+		Sort:      data.Sort,
+		Dir:       data.Dir,
+	}, nil
+}
+
+func (q DeviceQuery) readData() deviceReadData {
+	params := deviceReadParams{
+		Name:            q.Name,
+		IPAddress:       q.IPAddress,
+		DeviceClass:     q.DeviceClass,
+		ProductionState: q.ProductionState,
+		Groups:          q.Group,
+		Systems:         q.System,
+		Location:        q.Location,
+		Collector:       q.Collector,
+	}
+
+	data := deviceReadData{
+		UID:   q.UID,
+		Start: q.Start,
+		Limit: q.Limit,
+		Sort:  q.Sort,
+		Dir:   q.Dir,
+	}
+	if params.Name != "" || params.IPAddress != "" || params.DeviceClass != "" || len(params.ProductionState) > 0 ||
+		params.Groups != "" || params.Systems != "" || params.Location != "" || params.Collector != "" {
+		data.Params = &params
+	}
+	return data
+}
+
+// defaultPageSize is the number of devices fetched per page by DeviceIterator unless set by PageSize
+const defaultPageSize = 100
+
+// DeviceIterator walks all devices matching a query, fetching them page by page from Zenoss as needed.
+//
+//	it := client.Devices(ctx, query)
+//	for it.Next() {
+//		dev := it.Device()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type DeviceIterator struct {
+	ctx      context.Context
+	client   Client
+	query    DeviceQuery
+	prefetch bool
+	pageSize int
+	end      int
+
+	page      []Device
+	pos       int
+	hash      string
+	exhausted bool
+	pending   chan devicePage
+	cur       Device
+	err       error
+}
+
+type devicePage struct {
+	list *DeviceList
+	err  error
+}
+
+func (z *client) Devices(ctx context.Context, query DeviceQuery) *DeviceIterator {
+	it := &DeviceIterator{ctx: ctx, client: z, query: query, pageSize: defaultPageSize}
+	if query.Limit > 0 {
+		it.end = query.Start + query.Limit
+	}
+	return it
+}
+
+// Prefetch makes the iterator fetch the next page in the background while the current page is consumed
+func (it *DeviceIterator) Prefetch() *DeviceIterator {
+	it.prefetch = true
+	return it
+}
+
+// PageSize sets the number of devices fetched per page, default is 100. The limit of the query still caps
+// the number of devices returned in total.
+func (it *DeviceIterator) PageSize(n int) *DeviceIterator {
+	if n > 0 {
+		it.pageSize = n
+	}
+	return it
+}
+
+// Next advances to the next device returning false when there are no more devices or an error occurred
+func (it *DeviceIterator) Next() bool {
+	for {
+		if it.err != nil {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		if it.pos < len(it.page) {
+			it.cur = it.page[it.pos]
+			it.pos++
+			return true
+		}
+		if it.exhausted {
+			return false
+		}
+		it.nextPage()
+	}
+}
+
+// Device returns the current device
+func (it *DeviceIterator) Device() Device {
+	return it.cur
+}
+
+// Err returns the error which stopped the iteration, if any. If the devices matching the query are
+// changed while iterating the error wraps ErrHashMismatch.
+func (it *DeviceIterator) Err() error {
+	return it.err
+}
+
+func (it *DeviceIterator) nextPage() {
+	var p devicePage
+	if it.pending != nil {
+		select {
+		case p = <-it.pending:
+		case <-it.ctx.Done():
+			p.err = it.ctx.Err()
+		}
+		it.pending = nil
+	} else {
+		p.list, p.err = it.client.ListDevices(it.ctx, it.pageQuery())
+	}
+
+	if p.err != nil {
+		it.err = p.err
+		return
+	}
+	if it.hash != "" && p.list.Hash != it.hash {
+		it.err = fmt.Errorf("devices changed while iterating: %w", ErrHashMismatch)
+		return
+	}
+	it.hash = p.list.Hash
+
+	it.page = p.list.Devices
+	it.pos = 0
+	it.query.Start += len(p.list.Devices)
+	if len(p.list.Devices) == 0 || it.query.Start >= p.list.TotalCount || (it.end > 0 && it.query.Start >= it.end) {
+		it.exhausted = true
+		return
+	}
+
+	if it.prefetch {
+		ch := make(chan devicePage, 1)
+		go func(query DeviceQuery) {
+			list, err := it.client.ListDevices(it.ctx, query)
+			ch <- devicePage{list: list, err: err}
+		}(it.pageQuery())
+		it.pending = ch
+	}
+}
+
+// pageQuery returns the query for the next page, shortened so the limit of the query is not exceeded
+func (it *DeviceIterator) pageQuery() DeviceQuery {
+	q := it.query
+	q.Limit = it.pageSize
+	if it.end > 0 {
+		q.Limit = min(q.Limit, it.end-q.Start)
+	}
+	return q
+}
@@ -0,0 +1,751 @@
+package zenoss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDevices(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"DeviceRouter","method":"getDevices","data":[{"uid":"/zport/dmd/Devices/VirtualDevices","params":{"name":"oaas","productionState":[1000,500],"systems":"/Jysk/Development","collector":"localhost"},"start":50,"limit":25,"sort":"ipAddress","dir":"DESC"}],"tid":1}`, buf.String())
+		rw.Write([]byte(readDeviceResponse))
+	}))
+	defer server.Close()
+
+	list, err := api.ListDevices(context.Background(), DeviceQuery{
+		UID:             "/zport/dmd/Devices/VirtualDevices",
+		Name:            "oaas",
+		ProductionState: []ProductionState{ProductionStateProduction, ProductionStatePreProduction},
+		System:          "/Jysk/Development",
+		Collector:       "localhost",
+		Start:           50,
+		Limit:           25,
+		Sort:            "ipAddress",
+		Dir:             SortDescending,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, list.TotalCount)
+	assert.Equal(t, "1", list.Hash)
+	assert.Len(t, list.Devices, 1)
+	assert.Equal(t, "oaas1.k8s.jysk.netic.dk", list.Devices[0].Name)
+}
+
+func TestListDevicesEmpty(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"DeviceRouter","method":"getDevices","data":[{}],"tid":1}`, buf.String())
+		rw.Write([]byte(readDeviceResponseEmpty))
+	}))
+	defer server.Close()
+
+	list, err := api.ListDevices(context.Background(), DeviceQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, list.TotalCount)
+	assert.Empty(t, list.Devices)
+}
+
+// newPagedDevicesAPI serves getDevices pages of the given device names, changing the hash from the given tid
+func newPagedDevicesAPI(t *testing.T, names []string, changeAtTid int) (Client, *httptest.Server) {
+	return newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		var r struct {
+			Tid  int              `json:"tid"`
+			Data []deviceReadData `json:"data"`
+		}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&r))
+		start, limit := r.Data[0].Start, r.Data[0].Limit
+		end := min(start+limit, len(names))
+
+		devices := []Device{}
+		for _, n := range names[start:end] {
+			devices = append(devices, Device{UID: "/zport/dmd/Devices/devices/" + n, Name: n})
+		}
+		hash := "1"
+		if changeAtTid > 0 && r.Tid >= changeAtTid {
+			hash = "2"
+		}
+		json.NewEncoder(rw).Encode(map[string]interface{}{
+			"action": "DeviceRouter",
+			"method": "getDevices",
+			"tid":    r.Tid,
+			"type":   "rpc",
+			"result": map[string]interface{}{
+				"success":    true,
+				"totalCount": len(names),
+				"hash":       hash,
+				"devices":    devices,
+			},
+		})
+	}))
+}
+
+func TestDeviceIterator(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e"}
+	for _, prefetch := range []bool{false, true} {
+		api, server := newPagedDevicesAPI(t, names, 0)
+
+		it := api.Devices(context.Background(), DeviceQuery{}).PageSize(2)
+		if prefetch {
+			it = it.Prefetch()
+		}
+		var got []string
+		for it.Next() {
+			got = append(got, it.Device().Name)
+		}
+		assert.NoError(t, it.Err())
+		assert.Equal(t, names, got)
+		server.Close()
+	}
+}
+
+func TestDeviceIteratorLimit(t *testing.T) {
+	api, server := newPagedDevicesAPI(t, []string{"a", "b", "c", "d", "e"}, 0)
+	defer server.Close()
+
+	it := api.Devices(context.Background(), DeviceQuery{Start: 1, Limit: 3}).PageSize(2)
+	var got []string
+	for it.Next() {
+		got = append(got, it.Device().Name)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []string{"b", "c", "d"}, got)
+}
+
+func TestDeviceIteratorHashChanged(t *testing.T) {
+	api, server := newPagedDevicesAPI(t, []string{"a", "b", "c", "d", "e"}, 2)
+	defer server.Close()
+
+	it := api.Devices(context.Background(), DeviceQuery{}).PageSize(2)
+	n := 0
+	for it.Next() {
+		n++
+	}
+	assert.Equal(t, 2, n)
+	assert.ErrorIs(t, it.Err(), ErrHashMismatch)
+}
+
+const readIPv6DeviceResponse = `{
+	"uuid": "8d2a6d1c-3f4b-4f0e-9a57-3b1d0f6e2c11",
+	"action": "DeviceRouter",
+	"result": {
+	  "totalCount": 1,
+	  "hash": "1",
+	  "success": true,
+	  "devices": [
+		{
+		  "uid": "/zport/dmd/Devices/Server/Linux/devices/web6.netic.dk",
+		  "name": "web6.netic.dk",
+		  "ipAddress": 42540766411282592856903984951653826561,
+		  "ipAddressString": "2001:db8::1",
+		  "productionState": 1000
+		}
+	  ]
+	},
+	"tid": 1,
+	"type": "rpc",
+	"method": "getDevices"
+  }`
+
+func TestListDevicesIPv6(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(readIPv6DeviceResponse))
+	}))
+	defer server.Close()
+
+	list, err := api.ListDevices(context.Background(), DeviceQuery{Name: "web6"})
+	assert.NoError(t, err)
+	if assert.Len(t, list.Devices, 1) {
+		assert.Equal(t, "2001:db8::1", list.Devices[0].IPAddressString)
+		assert.Equal(t, json.Number("42540766411282592856903984951653826561"), list.Devices[0].IPAddress)
+	}
+}
+
+func TestDeviceIteratorCancelled(t *testing.T) {
+	api, server := newPagedDevicesAPI(t, []string{"a", "b", "c", "d", "e"}, 0)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it := api.Devices(ctx, DeviceQuery{}).PageSize(2).Prefetch()
+	assert.True(t, it.Next())
+	cancel()
+	assert.False(t, it.Next())
+	assert.ErrorIs(t, it.Err(), context.Canceled)
+}
+
+func TestDeviceModel(t *testing.T) {
+	var res deviceReadResponse
+	assert.NoError(t, json.Unmarshal([]byte(readDeviceResponse), &res))
+
+	dev := res.Result.Devices[0]
+	assert.Equal(t, "/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk", dev.UID)
+	assert.Equal(t, "10.238.84.99", dev.IPAddressString)
+	assert.Equal(t, json.Number("183391331"), dev.IPAddress)
+	assert.Equal(t, "localhost", dev.Collector)
+	assert.Equal(t, 3, dev.Priority)
+	assert.Equal(t, "Products.ZenModel.Device", dev.PythonClass)
+	assert.Nil(t, dev.HWManufacturer)
+	assert.Equal(t, []Organizer{{
+		UID:  "/zport/dmd/Systems/Jysk/Development",
+		Path: "/Systems/Jysk/Development",
+		UUID: "3c4b7db9-b102-45ea-ade9-62ebc2532ac1",
+		Name: "/Jysk/Development",
+	}}, dev.Systems)
+	assert.Len(t, dev.Groups, 2)
+	assert.Equal(t, "/Netic", dev.Location.Name)
+	assert.Equal(t, EventCounts{}, dev.Events)
+	assert.Nil(t, dev.Extra)
+}
+
+func TestDeviceModelExtra(t *testing.T) {
+	var dev Device
+	assert.NoError(t, json.Unmarshal([]byte(`{"uid": "/zport/dmd/Devices/device", "name": "device", "events": {"critical": {"count": 2, "acknowledged_count": 1}}, "memory": 2048, "hwManufacturer": {"uid": "/zport/dmd/Manufacturers/Dell", "name": "Dell"}}`), &dev))
+	assert.Equal(t, "device", dev.Name)
+	assert.Equal(t, EventCount{Count: 2, AcknowledgedCount: 1}, dev.Events.Critical)
+	assert.Equal(t, "Dell", dev.HWManufacturer.Name)
+	assert.Equal(t, map[string]json.RawMessage{"memory": json.RawMessage("2048")}, dev.Extra)
+}
+
+func TestGetDeviceInfo(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"DeviceRouter","method":"getInfo","data":[{"uid":"/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk"}],"tid":1}`, buf.String())
+		rw.Write([]byte(getInfoDeviceResponse))
+	}))
+	defer server.Close()
+
+	info, err := api.GetDeviceInfo(context.Background(), "/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk")
+	assert.NoError(t, err)
+	assert.Equal(t, "oaas1.k8s.jysk.netic.dk", info.ID)
+	assert.Equal(t, "Managed by GitOps", info.Comments)
+	assert.Equal(t, "12 days 3h:10m:05s", info.Uptime)
+	assert.Equal(t, "public", info.SNMPCommunity)
+	assert.Equal(t, "v2c", info.SNMPVersion)
+	assert.Equal(t, "R12-U4", info.RackSlot)
+	assert.Equal(t, "/zport/dmd/Devices/VirtualDevices/jysk-k8s", info.DeviceClass.UID)
+	assert.Equal(t, time.Unix(1589956412, 0), info.FirstSeen.Time)
+	assert.Equal(t, time.Date(2024, 1, 12, 12, 3, 44, 0, time.Local), info.LastChanged.Time)
+	assert.True(t, info.LastCollected.IsZero())
+	assert.Equal(t, json.RawMessage(`"15"`), info.Extra["cValue"])
+}
+
+func TestGetDeviceInfoKeys(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"DeviceRouter","method":"getInfo","data":[{"uid":"/zport/dmd/Devices/device","keys":["uptime","comments"]}],"tid":1}`, buf.String())
+		rw.Write([]byte(`{"action": "DeviceRouter", "method": "getInfo", "tid": 1, "type": "rpc", "result": {"data": {"uptime": "1 day", "comments": ""}, "success": true}}`))
+	}))
+	defer server.Close()
+
+	info, err := api.GetDeviceInfo(context.Background(), "/zport/dmd/Devices/device", "uptime", "comments")
+	assert.NoError(t, err)
+	assert.Equal(t, "1 day", info.Uptime)
+}
+
+const getInfoDeviceResponse = `{
+	"uuid": "0d5b4c3e-0a3a-4a24-9d5e-3f2f8f7a7e1b",
+	"action": "DeviceRouter",
+	"result": {
+	  "disabled": false,
+	  "data": {
+		"uid": "/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk",
+		"id": "oaas1.k8s.jysk.netic.dk",
+		"name": "oaas1.k8s.jysk.netic.dk",
+		"ipAddressString": "10.238.84.99",
+		"productionState": 1000,
+		"priority": 3,
+		"collector": "localhost",
+		"comments": "Managed by GitOps",
+		"uptime": "12 days 3h:10m:05s",
+		"firstSeen": 1589956412,
+		"lastChanged": "2024/01/12 12:03:44",
+		"lastCollected": "Not Modeled",
+		"snmpCommunity": "public",
+		"snmpVersion": "v2c",
+		"tagNumber": "",
+		"serialNumber": "",
+		"rackSlot": "R12-U4",
+		"links": "",
+		"deviceClass": {
+		  "uid": "/zport/dmd/Devices/VirtualDevices/jysk-k8s",
+		  "name": "/VirtualDevices/jysk-k8s"
+		},
+		"cValue": "15"
+	  },
+	  "success": true
+	},
+	"tid": 1,
+	"type": "rpc",
+	"method": "getInfo"
+  }`
+
+func TestUpdateDevice(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"DeviceRouter","method":"setInfo","data":[{"uid":"/zport/dmd/Devices/device","comments":"","priority":5,"serialNumber":"SN123","snmpCommunity":"secret"}],"tid":1}`, buf.String())
+		rw.Write([]byte(setInfoDeviceResponse))
+	}))
+	defer server.Close()
+
+	err := api.UpdateDevice(context.Background(), "/zport/dmd/Devices/device", DeviceUpdate{
+		Comments:      Ptr(""),
+		Priority:      Ptr(5),
+		SerialNumber:  Ptr("SN123"),
+		SNMPCommunity: Ptr("secret"),
+	})
+	assert.NoError(t, err)
+}
+
+func TestSetProductionState(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"DeviceRouter","method":"setProductionState","data":[{"uids":["/zport/dmd/Devices/a","/zport/dmd/Devices/b"],"hashcheck":"","prodState":300}],"tid":1}`, buf.String())
+		rw.Write([]byte(`{"action": "DeviceRouter", "method": "setProductionState", "tid": 1, "type": "rpc", "result": {"success": true}}`))
+	}))
+	defer server.Close()
+
+	err := api.SetProductionState(context.Background(), []string{"/zport/dmd/Devices/a", "/zport/dmd/Devices/b"}, 300)
+	assert.NoError(t, err)
+}
+
+func TestSetPriorityByQuery(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		if strings.Contains(buf.String(), "getDevices") {
+			assert.Equal(t, `{"action":"DeviceRouter","method":"getDevices","data":[{"uid":"/zport/dmd/Devices/VirtualDevices","params":{"systems":"/Jysk/Development"},"limit":1}],"tid":1}`, buf.String())
+			rw.Write([]byte(`{"action": "DeviceRouter", "method": "getDevices", "tid": 1, "type": "rpc", "result": {"totalCount": 42, "hash": "abc", "success": true, "devices": [{"uid": "/zport/dmd/Devices/a"}]}}`))
+			return
+		}
+		assert.Equal(t, `{"action":"DeviceRouter","method":"setPriority","data":[{"uids":[],"hashcheck":"abc","uid":"/zport/dmd/Devices/VirtualDevices","ranges":[[0,41]],"params":{"systems":"/Jysk/Development"},"priority":5}],"tid":2}`, buf.String())
+		rw.Write([]byte(`{"action": "DeviceRouter", "method": "setPriority", "tid": 2, "type": "rpc", "result": {"success": true}}`))
+	}))
+	defer server.Close()
+
+	err := api.SetPriorityByQuery(context.Background(), DeviceQuery{UID: "/zport/dmd/Devices/VirtualDevices", System: "/Jysk/Development"}, 5)
+	assert.NoError(t, err)
+}
+
+func TestListDevicesUnsuccessful(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"msg": "Permission denied", "success": false}, "tid": 1, "type": "rpc", "method": "getDevices"}`))
+	}))
+	defer server.Close()
+
+	_, err := api.ListDevices(context.Background(), DeviceQuery{Name: "oaas"})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	err = api.SetPriorityByQuery(context.Background(), DeviceQuery{Name: "oaas"}, 5)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestSetProductionStateByQueryNoMatch(t *testing.T) {
+	calls := 0
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+		rw.Write([]byte(readDeviceResponseEmpty))
+	}))
+	defer server.Close()
+
+	err := api.SetProductionStateByQuery(context.Background(), DeviceQuery{Name: "missing"}, 300)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestMoveDevices(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		switch {
+		case strings.Contains(buf.String(), "moveDevices"):
+			assert.Equal(t, `{"action":"DeviceRouter","method":"moveDevices","data":[{"uids":["/zport/dmd/Devices/VirtualDevices/shared-kubernetes/devices/oaas1.k8s.jysk.netic.dk"],"hashcheck":"","target":"/zport/dmd/Devices/VirtualDevices/jysk-k8s"}],"tid":1}`, buf.String())
+			rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"new_jobs": [{"uuid": "7b2e8c8e", "description": "Move device oaas1.k8s.jysk.netic.dk to /VirtualDevices/jysk-k8s"}], "success": true}, "tid": 1, "type": "rpc", "method": "moveDevices"}`))
+		case strings.Contains(buf.String(), "getDevices"):
+			assert.Equal(t, `[{"action":"DeviceRouter","method":"getDevices","data":[{"uid":"/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk"}],"tid":4},{"action":"DeviceRouter","method":"getInfo","data":[{"uid":"/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk","keys":["locking"]}],"tid":5}]`, buf.String())
+			rw.Write(batchResponse(t, buf.String(), readDeviceResponse, readDeviceLockingResponse))
+		case strings.Contains(buf.String(), "getInfo"):
+			rw.Write([]byte(`{"uuid": "2", "action": "JobsRouter", "result": {"data": {"uuid": "7b2e8c8e", "status": "SUCCESS"}, "success": true}, "tid": 2, "type": "rpc", "method": "getInfo"}`))
+		case strings.Contains(buf.String(), "detail"):
+			rw.Write([]byte(`{"uuid": "3", "action": "JobsRouter", "result": {"content": []}, "tid": 3, "type": "rpc", "method": "detail"}`))
+		}
+	}))
+	defer server.Close()
+
+	res, err := api.MoveDevices(context.Background(), []string{"/zport/dmd/Devices/VirtualDevices/shared-kubernetes/devices/oaas1.k8s.jysk.netic.dk"}, "/VirtualDevices/jysk-k8s")
+	assert.NoError(t, err)
+	assert.Equal(t, "7b2e8c8e", res.Job.UUID)
+	assert.Equal(t, []string{"/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk"}, res.UIDs)
+
+	devices, err := res.Resolve(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, devices, 1)
+	assert.Equal(t, "oaas1.k8s.jysk.netic.dk", devices[0].Name)
+}
+
+func TestDeviceClassUID(t *testing.T) {
+	assert.Equal(t, "/zport/dmd/Devices/Server/Linux", deviceClassUID("/Server/Linux"))
+	assert.Equal(t, "/zport/dmd/Devices/Server/Linux", deviceClassUID("/Devices/Server/Linux/"))
+	assert.Equal(t, "/zport/dmd/Devices/Server/Linux", deviceClassUID("/zport/dmd/Devices/Server/Linux"))
+	assert.Equal(t, "/zport/dmd/Devices/Server/Linux", deviceClassUID("Server/Linux"))
+}
+
+func TestRenameDevice(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"DeviceRouter","method":"renameDevice","data":[{"uid":"/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1","newId":"oaas2","retainGraphs":true}],"tid":1}`, buf.String())
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"uid": "/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas2", "success": true}, "tid": 1, "type": "rpc", "method": "renameDevice"}`))
+	}))
+	defer server.Close()
+
+	uid, err := api.RenameDevice(context.Background(), "/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1", "oaas2", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas2", uid)
+}
+
+func TestRenameDeviceConflict(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"msg": "Device oaas2 already exists", "success": false}, "tid": 1, "type": "rpc", "method": "renameDevice"}`))
+	}))
+	defer server.Close()
+
+	_, err := api.RenameDevice(context.Background(), "/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1", "oaas2", false)
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestResetIP(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"DeviceRouter","method":"resetIp","data":[{"uids":["/zport/dmd/Devices/a","/zport/dmd/Devices/b"],"hashcheck":"","ip":""}],"tid":1}`, buf.String())
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"msg": "Reset 2 IP addresses.", "success": true}, "tid": 1, "type": "rpc", "method": "resetIp"}`))
+	}))
+	defer server.Close()
+
+	assert.NoError(t, api.ResetIP(context.Background(), []string{"/zport/dmd/Devices/a", "/zport/dmd/Devices/b"}, ""))
+}
+
+func TestSetManageIPConflict(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"DeviceRouter","method":"setManageIp","data":[{"uid":"/zport/dmd/Devices/a","ip":"10.0.0.1"}],"tid":1}`, buf.String())
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"msg": "IP address 10.0.0.1 in use by device b", "success": false}, "tid": 1, "type": "rpc", "method": "setManageIp"}`))
+	}))
+	defer server.Close()
+
+	err := api.SetManageIP(context.Background(), "/zport/dmd/Devices/a", "10.0.0.1")
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestLockDevices(t *testing.T) {
+	bodies := []string{}
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		bodies = append(bodies, buf.String())
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"msg": "Locked 1 devices.", "success": true}, "tid": 1, "type": "rpc", "method": "lockDevices"}`))
+	}))
+	defer server.Close()
+
+	assert.NoError(t, api.LockDevices(context.Background(), []string{"/zport/dmd/Devices/a"}, LockOptions{Deletion: true, SendEvent: true}))
+	assert.NoError(t, api.UnlockDevices(context.Background(), []string{"/zport/dmd/Devices/a"}))
+	assert.Equal(t, []string{
+		`{"action":"DeviceRouter","method":"lockDevices","data":[{"uids":["/zport/dmd/Devices/a"],"hashcheck":"","updates":false,"deletion":true,"sendEvent":true}],"tid":1}`,
+		`{"action":"DeviceRouter","method":"lockDevices","data":[{"uids":["/zport/dmd/Devices/a"],"hashcheck":"","updates":false,"deletion":false,"sendEvent":false}],"tid":2}`,
+	}, bodies)
+}
+
+func TestDeleteDevicesRemoveFromOrganizer(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		if strings.Contains(buf.String(), "removeDevices") {
+			assert.Equal(t, `{"action":"DeviceRouter","method":"removeDevices","data":[{"action":"remove","uids":["/zport/dmd/Devices/a","/zport/dmd/Devices/b"],"hashcheck":"h1","uid":"/zport/dmd/Groups/SLA/Plus","deleteEvents":false,"deletePerf":false}],"tid":3}`, buf.String())
+			rw.Write([]byte(`{"uuid": "3", "action": "DeviceRouter", "result": {"success": true}, "tid": 3, "type": "rpc", "method": "removeDevices"}`))
+			return
+		}
+		assert.Equal(t, `[{"action":"DeviceRouter","method":"getDevices","data":[{"uid":"/zport/dmd/Devices/a"}],"tid":1},{"action":"DeviceRouter","method":"getDevices","data":[{"uid":"/zport/dmd/Devices/b"}],"tid":2}]`, buf.String())
+		rw.Write([]byte(`[
+			{"action": "DeviceRouter", "method": "getDevices", "tid": 1, "type": "rpc", "result": {"totalCount": 1, "hash": "h1", "success": true, "devices": [{"uid": "/zport/dmd/Devices/a"}]}},
+			{"action": "DeviceRouter", "method": "getDevices", "tid": 2, "type": "rpc", "result": {"totalCount": 1, "hash": "h2", "success": true, "devices": [{"uid": "/zport/dmd/Devices/b"}]}}
+		]`))
+	}))
+	defer server.Close()
+
+	err := api.DeleteDevices(context.Background(), []string{"/zport/dmd/Devices/a", "/zport/dmd/Devices/b"}, DeleteOptions{
+		Mode:      DeleteModeRemoveFromOrganizer,
+		Organizer: "/Groups/SLA/Plus",
+	})
+	assert.NoError(t, err)
+}
+
+func TestDeleteDevicesNotFound(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.NotContains(t, buf.String(), "removeDevices")
+		rw.Write([]byte(`[
+			{"action": "DeviceRouter", "method": "getDevices", "tid": 1, "type": "rpc", "result": {"totalCount": 1, "hash": "h1", "success": true, "devices": [{"uid": "/zport/dmd/Devices/a"}]}},
+			{"action": "DeviceRouter", "method": "getDevices", "tid": 2, "type": "rpc", "result": {"totalCount": 0, "hash": "", "success": true, "devices": []}}
+		]`))
+	}))
+	defer server.Close()
+
+	err := api.DeleteDevices(context.Background(), []string{"/zport/dmd/Devices/a", "/zport/dmd/Devices/b"}, DeleteOptions{DeleteEvents: true, DeletePerf: true})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorContains(t, err, "device /zport/dmd/Devices/b: not found")
+}
+
+func TestDeleteDevicesUnsuccessfulRead(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.NotContains(t, buf.String(), "removeDevices")
+		rw.Write([]byte(`{"action": "DeviceRouter", "method": "getDevices", "tid": 1, "type": "rpc", "result": {"totalCount": 0, "msg": "Permission denied", "success": false}}`))
+	}))
+	defer server.Close()
+
+	err := api.DeleteDevices(context.Background(), []string{"/zport/dmd/Devices/a"}, DeleteOptions{})
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	assert.NotErrorIs(t, err, ErrNotFound)
+
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+}
+
+func TestRemodelDevice(t *testing.T) {
+	status := "SUCCESS"
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		switch {
+		case strings.Contains(buf.String(), "remodel"):
+			assert.Equal(t, `{"action":"DeviceRouter","method":"remodel","data":[{"deviceUid":"/zport/dmd/Devices/a"}],"tid":1}`, buf.String())
+			rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"jobId": "5d4f0c1e", "success": true}, "tid": 1, "type": "rpc", "method": "remodel"}`))
+		case req.URL.Path == "/zport/dmd/jobs_router" && strings.Contains(buf.String(), "getInfo"):
+			fmt.Fprintf(rw, `{"uuid": "2", "action": "JobsRouter", "result": {"data": {"uuid": "5d4f0c1e", "status": %q}, "success": true}, "tid": 2, "type": "rpc", "method": "getInfo"}`, status)
+		case strings.Contains(buf.String(), "detail"):
+			rw.Write([]byte(jobLogResponse))
+		default:
+			assert.Equal(t, `{"action":"DeviceRouter","method":"getInfo","data":[{"uid":"/zport/dmd/Devices/a","keys":["lastCollected"]}],"tid":4}`, buf.String())
+			rw.Write([]byte(`{"uuid": "4", "action": "DeviceRouter", "result": {"data": {"lastCollected": "2023/11/14 22:13:51"}, "success": true}, "tid": 4, "type": "rpc", "method": "getInfo"}`))
+		}
+	}))
+	defer server.Close()
+
+	job, err := api.RemodelDevice(context.Background(), "/zport/dmd/Devices/a")
+	assert.NoError(t, err)
+	assert.Equal(t, "5d4f0c1e", job.UUID)
+
+	res, err := api.WaitForModel(context.Background(), "/zport/dmd/Devices/a", job)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 11, 14, 22, 13, 51, 0, time.UTC), res.LastCollected.UTC())
+	assert.Len(t, res.Log, 4)
+
+	status = "FAILURE"
+	res, err = api.WaitForModel(context.Background(), "/zport/dmd/Devices/a", job)
+	assert.ErrorIs(t, err, ErrJobFailed)
+	assert.Equal(t, JobFailure, res.Job.Status)
+	assert.True(t, res.LastCollected.IsZero())
+}
+
+func TestRemodelDeviceNoJob(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"success": true}, "tid": 1, "type": "rpc", "method": "remodel"}`))
+	}))
+	defer server.Close()
+
+	job, err := api.RemodelDevice(context.Background(), "/zport/dmd/Devices/a")
+	assert.EqualError(t, err, "remodel device of /zport/dmd/Devices/a returned no job")
+	assert.Nil(t, job)
+
+	_, err = api.WaitForModel(context.Background(), "/zport/dmd/Devices/a", job)
+	assert.EqualError(t, err, "unable to wait for modeling of /zport/dmd/Devices/a: no job given")
+}
+
+func TestEnsureDeviceExisting(t *testing.T) {
+	exists := []string{}
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		var r struct {
+			Method string `json:"method"`
+			Data   []struct {
+				UID string `json:"uid"`
+			} `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(buf.String()), &r))
+		switch r.Method {
+		case "objectExists":
+			exists = append(exists, r.Data[0].UID)
+			rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"exists": true, "success": true}, "tid": 1, "type": "rpc", "method": "objectExists"}`))
+		case "getDevices":
+			assert.Equal(t, `{"action":"DeviceRouter","method":"getDevices","data":[{"params":{"name":"oaas1.k8s.jysk.netic.dk"},"limit":100}],"tid":5}`, buf.String())
+			rw.Write([]byte(readDeviceResponse))
+		default:
+			t.Error("unexpected call", r.Method)
+		}
+	}))
+	defer server.Close()
+
+	device, err := api.EnsureDevice(context.Background(), NewDevice{
+		Name:         "oaas1.k8s.jysk.netic.dk",
+		Class:        "/VirtualDevices/shared-kubernetes",
+		GroupPaths:   []string{"/SLA/Plus/Ping_Only"},
+		SystemPaths:  []string{"/Netic/Test"},
+		LocationPath: "/Netic/DC4",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "oaas1.k8s.jysk.netic.dk", device.Name)
+	assert.Equal(t, []string{
+		"/zport/dmd/Groups/SLA/Plus/Ping_Only",
+		"/zport/dmd/Systems/Netic/Test",
+		"/zport/dmd/Locations/Netic/DC4",
+		"/zport/dmd/Devices/VirtualDevices/shared-kubernetes",
+	}, exists)
+}
+
+func TestEnsureDeviceCreates(t *testing.T) {
+	created, lookups := []string{}, 0
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		var r struct {
+			Method string `json:"method"`
+			Data   []struct {
+				UID        string `json:"uid"`
+				ContextUID string `json:"contextUid"`
+				ID         string `json:"id"`
+			} `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(buf.String()), &r))
+		switch r.Method {
+		case "objectExists":
+			exists := !strings.HasPrefix(r.Data[0].UID, "/zport/dmd/Groups/SLA/Plus")
+			rw.Write([]byte(fmt.Sprintf(`{"uuid": "1", "action": "DeviceRouter", "result": {"exists": %t, "success": true}, "tid": 1, "type": "rpc", "method": "objectExists"}`, exists)))
+		case "addNode":
+			created = append(created, r.Data[0].ContextUID+"/"+r.Data[0].ID)
+			rw.Write([]byte(`{"uuid": "2", "action": "DeviceRouter", "result": {"nodeConfig": {}, "success": true}, "tid": 2, "type": "rpc", "method": "addNode"}`))
+		case "getDevices":
+			// The name is matched as a pattern so web10 is returned as well
+			lookups++
+			assert.Contains(t, buf.String(), `"data":[{"params":{"name":"web1"},"limit":100}]`)
+			if lookups == 1 {
+				rw.Write([]byte(`{"uuid": "3", "action": "DeviceRouter", "result": {"totalCount": 1, "devices": [{"uid": "/zport/dmd/Devices/Server/Linux/devices/web10", "name": "web10"}], "success": true}, "tid": 3, "type": "rpc", "method": "getDevices"}`))
+				return
+			}
+			rw.Write([]byte(`{"uuid": "3", "action": "DeviceRouter", "result": {"totalCount": 2, "devices": [{"uid": "/zport/dmd/Devices/Server/Linux/devices/web1", "name": "web1"}, {"uid": "/zport/dmd/Devices/Server/Linux/devices/web10", "name": "web10"}], "success": true}, "tid": 3, "type": "rpc", "method": "getDevices"}`))
+		case "addDevice":
+			rw.Write([]byte(addDeviceResponse))
+		case "getInfo":
+			rw.Write([]byte(`{"uuid": "4", "action": "JobsRouter", "result": {"data": {"status": "SUCCESS"}, "success": true}, "tid": 4, "type": "rpc", "method": "getInfo"}`))
+		case "detail":
+			rw.Write([]byte(jobLogResponse))
+		default:
+			t.Error("unexpected call", r.Method)
+		}
+	}))
+	defer server.Close()
+
+	device, err := api.EnsureDevice(context.Background(), NewDevice{
+		Name:       "web1",
+		Class:      "/Server/Linux",
+		GroupPaths: []string{"/SLA/Plus/Ping_Only"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "/zport/dmd/Devices/Server/Linux/devices/web1", device.UID)
+	assert.Equal(t, 2, lookups)
+	assert.Equal(t, []string{
+		"/zport/dmd/Groups/SLA/Plus",
+		"/zport/dmd/Groups/SLA/Plus/Ping_Only",
+	}, created)
+}
+
+func TestEnsureDeviceOrganizerForms(t *testing.T) {
+	exists := []string{}
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		var r struct {
+			Method string `json:"method"`
+			Data   []struct {
+				UID string `json:"uid"`
+			} `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(buf.String()), &r))
+		switch r.Method {
+		case "objectExists":
+			exists = append(exists, r.Data[0].UID)
+			rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"exists": true, "success": true}, "tid": 1, "type": "rpc", "method": "objectExists"}`))
+		case "getDevices":
+			rw.Write([]byte(readDeviceResponse))
+		default:
+			t.Error("unexpected call", r.Method)
+		}
+	}))
+	defer server.Close()
+
+	expected := []string{
+		"/zport/dmd/Groups/SLA/Plus",
+		"/zport/dmd/Systems/Netic/Test",
+		"/zport/dmd/Locations/Netic/DC4",
+		"/zport/dmd/Devices/VirtualDevices/shared-kubernetes",
+	}
+	for _, dev := range []NewDevice{
+		{Class: "/VirtualDevices/shared-kubernetes", GroupPaths: []string{"/SLA/Plus"}, SystemPaths: []string{"/Netic/Test"}, LocationPath: "/Netic/DC4"},
+		{Class: "VirtualDevices/shared-kubernetes", GroupPaths: []string{"SLA/Plus"}, SystemPaths: []string{"Netic/Test/"}, LocationPath: "Netic/DC4"},
+		{Class: "/Devices/VirtualDevices/shared-kubernetes", GroupPaths: []string{"/Groups/SLA/Plus"}, SystemPaths: []string{"/Systems/Netic/Test"}, LocationPath: "/Locations/Netic/DC4"},
+		{Class: "/zport/dmd/Devices/VirtualDevices/shared-kubernetes", GroupPaths: []string{"/zport/dmd/Groups/SLA/Plus"}, SystemPaths: []string{"/zport/dmd/Systems/Netic/Test"}, LocationPath: "/zport/dmd/Locations/Netic/DC4"},
+	} {
+		exists = exists[:0]
+		dev.Name = "oaas1.k8s.jysk.netic.dk"
+		_, err := api.EnsureDevice(context.Background(), dev)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, exists, dev.Class)
+	}
+}
+
+func TestDevicesNamed(t *testing.T) {
+	// The match is on the second page and the hash changes before it is read
+	names := make([]string, defaultPageSize+10)
+	for i := range names {
+		names[i] = fmt.Sprintf("web1%d", i)
+	}
+	names[len(names)-1] = "xweb1"
+	api, server := newPagedDevicesAPI(t, names, 2)
+	defer server.Close()
+
+	devices, err := api.(*client).devicesNamed(context.Background(), "xweb1")
+	assert.NoError(t, err)
+	if assert.Len(t, devices, 1) {
+		assert.Equal(t, "/zport/dmd/Devices/devices/xweb1", devices[0].UID)
+	}
+}
+
+func TestDevicesNamedBounded(t *testing.T) {
+	names := make([]string, 2*maxNameMatches)
+	for i := range names {
+		names[i] = fmt.Sprintf("web1%d", i)
+	}
+	names[len(names)-1] = "web1"
+	api, server := newPagedDevicesAPI(t, names, 0)
+	defer server.Close()
+
+	_, err := api.(*client).devicesNamed(context.Background(), "web1")
+	assert.EqualError(t, err, "more than 1000 devices match name web1")
+}
@@ -0,0 +1,190 @@
+package zenoss
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the requested object does not exist in Zenoss
+	ErrNotFound = errors.New("not found")
+
+	// ErrUnauthorized is returned when Zenoss rejects the credentials or the user lacks permissions
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrHashMismatch is returned when the hashcheck given to Zenoss no longer matches the data
+	ErrHashMismatch = errors.New("hash mismatch")
+
+	// ErrMultipleResults is returned when a lookup expected to match one object matched more
+	ErrMultipleResults = errors.New("multiple results")
+
+	// ErrUnavailable is returned when Zenoss, or a proxy in front of it, is temporarily unavailable
+	ErrUnavailable = errors.New("service unavailable")
+
+	// ErrUnexpectedContentType is returned when Zenoss responds with something other than JSON, e.g. a HTML page
+	ErrUnexpectedContentType = errors.New("unexpected content type")
+
+	// ErrResponseTooLarge is returned when the response body exceeds the configured maximum size
+	ErrResponseTooLarge = errors.New("response too large")
+
+	// ErrConflict is returned when Zenoss rejects a change because the name or address is already in use
+	ErrConflict = errors.New("conflict")
+
+	// ErrLocked is returned when Zenoss refuses to change or delete a device because it is locked
+	ErrLocked = errors.New("locked")
+
+	// ErrJobFailed is returned when a Zenoss job finishes without succeeding, see JobError
+	ErrJobFailed = errors.New("job failed")
+)
+
+// maxBodyExcerpt is the maximum number of bytes of a response body included in errors
+const maxBodyExcerpt = 512
+
+// APIError is returned when Zenoss reports a router call as failed, either by an
+// Ext.Direct exception or by an unsuccessful result
+type APIError struct {
+	// Router is the Ext.Direct action, e.g. DeviceRouter
+	Router string
+
+	// Method is the router method called, e.g. getDevices
+	Method string
+
+	// Tid is the transaction id of the call
+	Tid int
+
+	// Msg is the message returned by Zenoss, if any
+	Msg string
+
+	// HTTPStatus is the HTTP status code of the response carrying the error
+	HTTPStatus int
+
+	// Err is the sentinel classifying the error, e.g. ErrNotFound, or nil if unknown
+	Err error
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "zenoss %s.%s (tid %d) failed", e.Router, e.Method, e.Tid)
+	if e.HTTPStatus != 0 && e.HTTPStatus != http.StatusOK {
+		fmt.Fprintf(&b, " with status %d", e.HTTPStatus)
+	}
+	if e.Msg != "" {
+		fmt.Fprintf(&b, ": %s", e.Msg)
+	}
+	return b.String()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPError is returned when Zenoss does not respond with a successful JSON response,
+// e.g. on a non-2xx status code, a login page or a body exceeding the size limit
+type HTTPError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// ContentType is the content type of the response
+	ContentType string
+
+	// Body is an excerpt of the response body truncated to a bounded length
+	Body string
+
+	// Err is the sentinel classifying the error, e.g. ErrUnauthorized, or nil if unknown
+	Err error
+
+	retryAfter     time.Duration
+	sessionExpired bool
+}
+
+func (e *HTTPError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "zenoss responded with status %d", e.StatusCode)
+	if e.ContentType != "" {
+		fmt.Fprintf(&b, " (%s)", e.ContentType)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %s", e.Err)
+	}
+	if e.Body != "" {
+		fmt.Fprintf(&b, " - response: %s", e.Body)
+	}
+	return b.String()
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// isSessionExpired reports whether the error is caused by an expired login session
+func isSessionExpired(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.sessionExpired
+}
+
+// excerpt returns the body truncated to maxBodyExcerpt bytes for inclusion in errors
+func excerpt(body []byte) string {
+	if len(body) <= maxBodyExcerpt {
+		return strings.ToValidUTF8(string(body), "")
+	}
+	return strings.ToValidUTF8(string(body[:maxBodyExcerpt]), "") + "..."
+}
+
+// classify maps an HTTP status and Zenoss message to one of the sentinel errors
+func classify(status int, msg string) error {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrUnavailable
+	}
+
+	m := strings.ToLower(msg)
+	switch {
+	case strings.Contains(m, "hashcheck") || strings.Contains(m, "hash check"):
+		return ErrHashMismatch
+	case strings.Contains(m, "already exists") || strings.Contains(m, "already in use") || strings.Contains(m, "in use by") ||
+		strings.Contains(m, "duplicate"):
+		return ErrConflict
+	case strings.Contains(m, "locked"):
+		return ErrLocked
+	case strings.Contains(m, "not found") || strings.Contains(m, "notfound") || strings.Contains(m, "does not exist"):
+		return ErrNotFound
+	case strings.Contains(m, "unauthorized") || strings.Contains(m, "not authorized") || strings.Contains(m, "permission"):
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// JobError is returned when a Zenoss job finishes without succeeding. It wraps ErrJobFailed.
+type JobError struct {
+	// Job is the job as reported by Zenoss when it finished
+	Job *JobInfo
+
+	// Reason is the error logged by the job, if any
+	Reason string
+}
+
+func (e *JobError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "zenoss job %s", e.Job.UUID)
+	if e.Job.Description != "" {
+		fmt.Fprintf(&b, " (%s)", e.Job.Description)
+	}
+	fmt.Fprintf(&b, " finished with status %s", e.Job.Status)
+	if e.Reason != "" {
+		fmt.Fprintf(&b, ": %s", e.Reason)
+	}
+	return b.String()
+}
+
+func (e *JobError) Unwrap() error {
+	return ErrJobFailed
+}
@@ -0,0 +1,141 @@
+package zenoss
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExceptionResponse(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(exceptionResponse))
+	}))
+	defer server.Close()
+
+	err := api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300)
+	assert.ErrorIs(t, err, ErrHashMismatch)
+
+	var apiErr *APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, "DeviceRouter", apiErr.Router)
+		assert.Equal(t, "setInfo", apiErr.Method)
+		assert.Equal(t, 1, apiErr.Tid)
+		assert.Equal(t, "Hashcheck 2 does not match 1", apiErr.Msg)
+		assert.Equal(t, http.StatusOK, apiErr.HTTPStatus)
+	}
+}
+
+func TestUnsuccessfulResultMessage(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"uuid": "27bd2f0b-f865-4c3e-9268-0f0ae2053215", "action": "PropertiesRouter", "result": {"msg": "Property cValue not found", "success": false}, "tid": 1, "type": "rpc", "method": "update"}`))
+	}))
+	defer server.Close()
+
+	err := api.UpdateCustomProperty(context.Background(), "/zport/dmd/Devices/device", "cValue", "15")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	var apiErr *APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, "PropertiesRouter", apiErr.Router)
+		assert.Equal(t, "Property cValue not found", apiErr.Msg)
+	}
+}
+
+func TestReadDeviceMultipleResults(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		rw.Write(batchResponse(t, buf.String(), `{"action": "DeviceRouter", "result": {"totalCount": 2, "hash": "1", "success": true, "devices": [{"uid": "a"}, {"uid": "b"}]}, "type": "rpc", "method": "getDevices"}`, readDeviceLockingResponse))
+	}))
+	defer server.Close()
+
+	_, err := api.ReadDevice(context.Background(), "/zport/dmd/Devices")
+	assert.True(t, errors.Is(err, ErrMultipleResults))
+}
+
+func TestHTTPStatusErrors(t *testing.T) {
+	for status, sentinel := range map[int]error{
+		http.StatusUnauthorized:       ErrUnauthorized,
+		http.StatusBadGateway:         ErrUnavailable,
+		http.StatusServiceUnavailable: ErrUnavailable,
+	} {
+		api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Type", "text/html")
+			rw.WriteHeader(status)
+			rw.Write([]byte("<html><body>" + strings.Repeat("x", 2*maxBodyExcerpt) + "</body></html>"))
+		}))
+
+		_, err := api.ReadDevice(context.Background(), "/zport/dmd/Devices/device")
+		assert.ErrorIs(t, err, sentinel)
+
+		var httpErr *HTTPError
+		if assert.ErrorAs(t, err, &httpErr) {
+			assert.Equal(t, status, httpErr.StatusCode)
+			assert.Equal(t, "text/html", httpErr.ContentType)
+			assert.Len(t, httpErr.Body, maxBodyExcerpt+3)
+		}
+		server.Close()
+	}
+}
+
+func TestHTMLResponse(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		rw.Write([]byte("<html><body>Maintenance</body></html>"))
+	}))
+	defer server.Close()
+
+	_, err := api.ReadDevice(context.Background(), "/zport/dmd/Devices/device")
+	assert.ErrorIs(t, err, ErrUnexpectedContentType)
+}
+
+func TestLoginPageRedirect(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if strings.HasSuffix(req.URL.Path, "/login_form") {
+			rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+			rw.Write([]byte("<html><body>Login</body></html>"))
+			return
+		}
+		http.Redirect(rw, req, "/zport/acl_users/cookieAuthHelper/login_form?came_from="+req.URL.Path, http.StatusFound)
+	}))
+	defer server.Close()
+
+	_, err := api.ReadDevice(context.Background(), "/zport/dmd/Devices/device")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestResponseTooLarge(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(readDeviceResponse))
+	}))
+	defer server.Close()
+	api.(*client).maxResponseSize = 100
+
+	_, err := api.ReadDevice(context.Background(), "/zport/dmd/Devices/device")
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+}
+
+func TestClassify(t *testing.T) {
+	assert.Equal(t, ErrUnauthorized, classify(http.StatusUnauthorized, ""))
+	assert.Equal(t, ErrNotFound, classify(http.StatusNotFound, ""))
+	assert.Equal(t, ErrNotFound, classify(http.StatusOK, "ObjectNotFound: /zport/dmd/Devices/foo"))
+	assert.Equal(t, ErrHashMismatch, classify(http.StatusOK, "Hashcheck 2 does not match 1"))
+	assert.Equal(t, ErrConflict, classify(http.StatusOK, "Device oaas2.k8s.jysk.netic.dk already exists"))
+	assert.Equal(t, ErrConflict, classify(http.StatusOK, "IP address 10.0.0.1 in use by device oaas1.k8s.jysk.netic.dk"))
+	assert.Equal(t, ErrLocked, classify(http.StatusOK, "Device oaas1.k8s.jysk.netic.dk is locked from deletion"))
+	assert.Nil(t, classify(http.StatusOK, "something else"))
+}
+
+const exceptionResponse = `{
+	"type": "exception",
+	"action": "DeviceRouter",
+	"method": "setInfo",
+	"tid": 1,
+	"message": "Hashcheck 2 does not match 1",
+	"where": "Traceback (most recent call last): ..."
+  }`
@@ -0,0 +1,268 @@
+package zenoss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultJobPollInterval is how often jobs are polled while waiting for them unless configured otherwise
+	defaultJobPollInterval = 2 * time.Second
+
+	// defaultJobTimeout is how long to wait for a job unless configured otherwise
+	defaultJobTimeout = 10 * time.Minute
+)
+
+// JobStatus is the status of a Zenoss job
+type JobStatus string
+
+const (
+	// JobPending is the status of a job waiting to be run
+	JobPending = JobStatus("PENDING")
+
+	// JobStarted is the status of a running job
+	JobStarted = JobStatus("STARTED")
+
+	// JobRetry is the status of a job waiting to be retried
+	JobRetry = JobStatus("RETRY")
+
+	// JobSuccess is the status of a job which finished successfully
+	JobSuccess = JobStatus("SUCCESS")
+
+	// JobFailure is the status of a job which failed
+	JobFailure = JobStatus("FAILURE")
+
+	// JobAborted is the status of a job aborted while running
+	JobAborted = JobStatus("ABORTED")
+
+	// JobRevoked is the status of a job cancelled before it was run
+	JobRevoked = JobStatus("REVOKED")
+)
+
+// Done returns true if the job has finished, successfully or not
+func (s JobStatus) Done() bool {
+	switch s {
+	case JobSuccess, JobFailure, JobAborted, JobRevoked:
+		return true
+	}
+	return false
+}
+
+// JobInfo is a job run by Zenoss, e.g. for adding or modeling a device
+type JobInfo struct {
+	UUID        string          `json:"uuid"`
+	Type        string          `json:"type"`
+	Description string          `json:"description"`
+	Status      JobStatus       `json:"status"`
+	User        string          `json:"user"`
+	Scheduled   Timestamp       `json:"scheduled"`
+	Started     Timestamp       `json:"started"`
+	Finished    Timestamp       `json:"finished"`
+	Result      json.RawMessage `json:"result,omitempty"`
+
+	// Log is the log of the job, only set by WaitForJob once the job has finished
+	Log []string `json:"-"`
+}
+
+type jobData struct {
+	JobID string `json:"jobid"`
+}
+
+type jobInfoResponse struct {
+	response
+	Result jobInfoResult `json:"result"`
+}
+
+type jobInfoResult struct {
+	result
+	Data JobInfo `json:"data"`
+}
+
+type jobDetailResponse struct {
+	response
+	Result jobDetailResult `json:"result"`
+}
+
+type jobDetailResult struct {
+	Content []string `json:"content"`
+	Logfile string   `json:"logfile"`
+}
+
+func (z *client) GetJob(ctx context.Context, uuid string) (*JobInfo, error) {
+	req := request{
+		Action: actionJobsRouter,
+		Method: methodGetInfo,
+		Data:   []interface{}{jobData{JobID: uuid}},
+	}
+	var res jobInfoResponse
+	err := z.doRequest(ctx, req, pathJobsRouter, &res)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read job: %w", err)
+	}
+
+	if !res.Result.Success {
+		return nil, fmt.Errorf("read job returned unsuccessful: %w", res.failure(res.Result.result))
+	}
+
+	return &res.Result.Data, nil
+}
+
+func (z *client) JobLog(ctx context.Context, uuid string) ([]string, error) {
+	req := request{
+		Action: actionJobsRouter,
+		Method: methodJobDetail,
+		Data:   []interface{}{jobData{JobID: uuid}},
+	}
+	var res jobDetailResponse
+	err := z.doRequest(ctx, req, pathJobsRouter, &res)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read job log: %w", err)
+	}
+	return res.Result.Content, nil
+}
+
+func (z *client) WaitForJob(ctx context.Context, uuid string) (*JobInfo, error) {
+	interval, timeout := z.jobPollInterval, z.jobTimeout
+	if interval <= 0 {
+		interval = defaultJobPollInterval
+	}
+	if timeout <= 0 {
+		timeout = defaultJobTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		job, err := z.GetJob(ctx, uuid)
+		if err != nil {
+			return nil, fmt.Errorf("unable to wait for job %s: %w", uuid, err)
+		}
+
+		if job.Status.Done() {
+			job.Log, err = z.JobLog(ctx, uuid)
+			if err != nil {
+				return nil, fmt.Errorf("unable to wait for job %s: %w", uuid, err)
+			}
+			if job.Status != JobSuccess {
+				return job, &JobError{Job: job, Reason: jobFailureReason(job.Log)}
+			}
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("gave up waiting for job %s with status %s: %w", uuid, job.Status, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// jobFailureReason returns the last error logged by a job, or the last line of the log if no errors were logged
+func jobFailureReason(log []string) string {
+	reason := ""
+	for _, line := range log {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.Contains(line, " ERROR ") || !strings.Contains(reason, " ERROR ") {
+			reason = line
+		}
+	}
+	return reason
+}
+
+// Job is a handle on a job started in Zenoss, e.g. by CreateDeviceAsync
+type Job struct {
+	// UUID identifies the job in Zenoss
+	UUID string
+
+	// Description is the description of the job given by Zenoss when it was started
+	Description string
+
+	// Chained are the jobs started together with this job which run after it, e.g. modeling of a new device
+	Chained []*Job
+
+	client Client
+}
+
+// newJob returns a handle on the first of the given jobs with the rest chained, or nil if no jobs were started
+func (z *client) newJob(refs []jobRef) *Job {
+	if len(refs) == 0 {
+		return nil
+	}
+	job := &Job{UUID: refs[0].UUID, Description: refs[0].Description, client: z}
+	for _, ref := range refs[1:] {
+		job.Chained = append(job.Chained, &Job{UUID: ref.UUID, Description: ref.Description, client: z})
+	}
+	return job
+}
+
+// Status returns the current status of the job
+func (j *Job) Status(ctx context.Context) (*JobInfo, error) {
+	return j.client.GetJob(ctx, j.UUID)
+}
+
+// Wait waits for the job and then the chained jobs to finish returning the job as reported when it finished.
+// If the job or a chained job did not succeed *JobError is returned.
+func (j *Job) Wait(ctx context.Context) (*JobInfo, error) {
+	info, err := j.client.WaitForJob(ctx, j.UUID)
+	if err != nil {
+		return info, err
+	}
+	for _, c := range j.Chained {
+		if _, err := c.Wait(ctx); err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+// Log returns the log of the job
+func (j *Job) Log(ctx context.Context) ([]string, error) {
+	return j.client.JobLog(ctx, j.UUID)
+}
+
+// Abort aborts the job and the chained jobs
+func (j *Job) Abort(ctx context.Context) error {
+	uuids := []string{j.UUID}
+	for _, c := range j.Chained {
+		uuids = append(uuids, c.UUID)
+	}
+	return j.client.AbortJobs(ctx, uuids)
+}
+
+type jobAbortData struct {
+	JobIDs []string `json:"jobids"`
+}
+
+type jobAbortResponse struct {
+	response
+	Result result `json:"result"`
+}
+
+func (z *client) AbortJobs(ctx context.Context, uuids []string) error {
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	req := request{
+		Action: actionJobsRouter,
+		Method: methodJobAbort,
+		Data:   []interface{}{jobAbortData{JobIDs: uuids}},
+	}
+	var res jobAbortResponse
+	err := z.doRequest(ctx, req, pathJobsRouter, &res)
+	if err != nil {
+		return fmt.Errorf("unable to abort jobs: %w", err)
+	}
+
+	if !res.Result.Success {
+		return fmt.Errorf("abort jobs returned unsuccessful: %w", res.failure(res.Result))
+	}
+
+	return nil
+}
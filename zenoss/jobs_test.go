@@ -0,0 +1,176 @@
+package zenoss
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const getJobInfoResponse = `{
+	"uuid": "1",
+	"action": "JobsRouter",
+	"result": {
+	  "data": {
+		"uuid": "fe2a1578-0d89-4370-ab0d-d4cc86d1ca73",
+		"type": "DeviceCreationJob",
+		"description": "Discover and model device oaas1.k8s.jysk.netic.dk as /VirtualDevices/shared-kubernetes",
+		"status": "FAILURE",
+		"user": "admin",
+		"scheduled": 1700000000.5,
+		"started": 1700000001.0,
+		"finished": 1700000031.0
+	  },
+	  "success": true
+	},
+	"tid": 1,
+	"type": "rpc",
+	"method": "getInfo"
+  }`
+
+const jobLogResponse = `{
+	"uuid": "2",
+	"action": "JobsRouter",
+	"result": {
+	  "content": [
+		"2023-11-14 22:13:21,000 INFO zen.ZenModeler: Collecting for device oaas1.k8s.jysk.netic.dk",
+		"2023-11-14 22:13:51,000 ERROR zen.ZenModeler: Timeout connecting to oaas1.k8s.jysk.netic.dk",
+		"2023-11-14 22:13:51,100 INFO zen.Job: Job finished",
+		""
+	  ],
+	  "logfile": "/opt/zenoss/log/jobs/fe2a1578-0d89-4370-ab0d-d4cc86d1ca73.log"
+	},
+	"tid": 2,
+	"type": "rpc",
+	"method": "detail"
+  }`
+
+func TestWaitForJobFailed(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/zport/dmd/jobs_router", req.URL.Path)
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		if strings.Contains(buf.String(), "detail") {
+			assert.Equal(t, `{"action":"JobsRouter","method":"detail","data":[{"jobid":"fe2a1578-0d89-4370-ab0d-d4cc86d1ca73"}],"tid":2}`, buf.String())
+			rw.Write([]byte(jobLogResponse))
+		} else {
+			assert.Equal(t, `{"action":"JobsRouter","method":"getInfo","data":[{"jobid":"fe2a1578-0d89-4370-ab0d-d4cc86d1ca73"}],"tid":1}`, buf.String())
+			rw.Write([]byte(getJobInfoResponse))
+		}
+	}))
+	defer server.Close()
+
+	job, err := api.WaitForJob(context.Background(), "fe2a1578-0d89-4370-ab0d-d4cc86d1ca73")
+	assert.ErrorIs(t, err, ErrJobFailed)
+	assert.ErrorContains(t, err, "finished with status FAILURE: 2023-11-14 22:13:51,000 ERROR zen.ZenModeler: Timeout connecting to oaas1.k8s.jysk.netic.dk")
+
+	var jobErr *JobError
+	assert.True(t, errors.As(err, &jobErr))
+	assert.Equal(t, job, jobErr.Job)
+	assert.Equal(t, "DeviceCreationJob", job.Type)
+	assert.Equal(t, time.Unix(1700000031, 0), job.Finished.Time)
+	assert.Len(t, job.Log, 4)
+}
+
+func TestWaitForJobTimeout(t *testing.T) {
+	polls := 0
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		polls++
+		rw.Write([]byte(`{"uuid": "1", "action": "JobsRouter", "result": {"data": {"uuid": "c3ab77cf", "status": "PENDING"}, "success": true}, "tid": 1, "type": "rpc", "method": "getInfo"}`))
+	}))
+	defer server.Close()
+	api.(*client).jobPollInterval = 10 * time.Millisecond
+	api.(*client).jobTimeout = 35 * time.Millisecond
+
+	_, err := api.WaitForJob(context.Background(), "c3ab77cf")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "gave up waiting for job c3ab77cf with status PENDING")
+	assert.GreaterOrEqual(t, polls, 3)
+}
+
+func TestJobFailureReason(t *testing.T) {
+	assert.Equal(t, "", jobFailureReason(nil))
+	assert.Equal(t, "Job aborted", jobFailureReason([]string{"Starting", "Job aborted", " "}))
+}
+
+func TestCreateDeviceAsync(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		switch {
+		case strings.Contains(buf.String(), "addDevice"):
+			rw.Write([]byte(addDeviceResponse))
+		case strings.Contains(buf.String(), "abort"):
+			assert.Equal(t, "/zport/dmd/jobs_router", req.URL.Path)
+			assert.Equal(t, `{"action":"JobsRouter","method":"abort","data":[{"jobids":["c3ab77cf-fdea-4daa-8c3e-7131049439d1","fe2a1578-0d89-4370-ab0d-d4cc86d1ca73"]}],"tid":4}`, buf.String())
+			rw.Write([]byte(`{"uuid": "4", "action": "JobsRouter", "result": {"success": true}, "tid": 4, "type": "rpc", "method": "abort"}`))
+		case strings.Contains(buf.String(), "detail"):
+			rw.Write([]byte(jobLogResponse))
+		default:
+			assert.Equal(t, `{"action":"JobsRouter","method":"getInfo","data":[{"jobid":"c3ab77cf-fdea-4daa-8c3e-7131049439d1"}],"tid":2}`, buf.String())
+			rw.Write([]byte(`{"uuid": "2", "action": "JobsRouter", "result": {"data": {"uuid": "c3ab77cf-fdea-4daa-8c3e-7131049439d1", "status": "STARTED"}, "success": true}, "tid": 2, "type": "rpc", "method": "getInfo"}`))
+		}
+	}))
+	defer server.Close()
+
+	job, err := api.CreateDeviceAsync(context.Background(), NewDevice{Name: "oaas1.k8s.jysk.netic.dk", Class: "/VirtualDevices/shared-kubernetes"})
+	assert.NoError(t, err)
+	assert.Equal(t, "c3ab77cf-fdea-4daa-8c3e-7131049439d1", job.UUID)
+	assert.Equal(t, "Create prod1.netic-platform.shared.k8s.netic.dk under /VirtualDevices/shared-kubernetes", job.Description)
+	assert.Len(t, job.Chained, 1)
+	assert.Equal(t, "fe2a1578-0d89-4370-ab0d-d4cc86d1ca73", job.Chained[0].UUID)
+
+	status, err := job.Status(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, JobStarted, status.Status)
+
+	log, err := job.Log(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, log, 4)
+
+	assert.NoError(t, job.Abort(context.Background()))
+}
+
+func TestCreateDeviceAsyncNoJob(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"new_jobs": [], "success": true}, "tid": 1, "type": "rpc", "method": "addDevice"}`))
+	}))
+	defer server.Close()
+
+	job, err := api.CreateDeviceAsync(context.Background(), NewDevice{Name: "oaas1.k8s.jysk.netic.dk", Class: "/VirtualDevices/shared-kubernetes"})
+	assert.EqualError(t, err, "add device of oaas1.k8s.jysk.netic.dk returned no job")
+	assert.Nil(t, job)
+
+	_, err = api.CreateDevice(context.Background(), NewDevice{Name: "oaas1.k8s.jysk.netic.dk", Class: "/VirtualDevices/shared-kubernetes"})
+	assert.Error(t, err)
+}
+
+func TestJobWaitChainedFailure(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		switch {
+		case strings.Contains(buf.String(), "detail"):
+			rw.Write([]byte(jobLogResponse))
+		case strings.Contains(buf.String(), "fe2a1578-0d89-4370-ab0d-d4cc86d1ca73"):
+			rw.Write([]byte(getJobInfoResponse))
+		default:
+			rw.Write([]byte(`{"uuid": "1", "action": "JobsRouter", "result": {"data": {"uuid": "c3ab77cf-fdea-4daa-8c3e-7131049439d1", "status": "SUCCESS"}, "success": true}, "tid": 1, "type": "rpc", "method": "getInfo"}`))
+		}
+	}))
+	defer server.Close()
+
+	job := api.(*client).newJob([]jobRef{{UUID: "c3ab77cf-fdea-4daa-8c3e-7131049439d1"}, {UUID: "fe2a1578-0d89-4370-ab0d-d4cc86d1ca73"}})
+	info, err := job.Wait(context.Background())
+	assert.ErrorIs(t, err, ErrJobFailed)
+	assert.Equal(t, JobSuccess, info.Status)
+
+	var jobErr *JobError
+	assert.True(t, errors.As(err, &jobErr))
+	assert.Equal(t, "fe2a1578-0d89-4370-ab0d-d4cc86d1ca73", jobErr.Job.UUID)
+}
@@ -0,0 +1,113 @@
+package zenoss
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+// limiter throttles requests to Zenoss using a token bucket and caps the number of requests in flight
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	inFlight chan struct{}
+	observe  func(time.Duration)
+}
+
+// newLimiter returns a limiter for the configuration or nil if no limits are configured
+func newLimiter(cfg *config) *limiter {
+	if cfg.rateLimit <= 0 && cfg.maxInFlight <= 0 {
+		return nil
+	}
+
+	l := &limiter{
+		rate:    cfg.rateLimit,
+		burst:   math.Max(float64(cfg.rateBurst), 1),
+		observe: cfg.throttleObserver,
+	}
+	l.tokens = l.burst
+	if cfg.maxInFlight > 0 {
+		l.inFlight = make(chan struct{}, cfg.maxInFlight)
+	}
+	return l
+}
+
+// acquire waits until a request is allowed and returns the function releasing its in flight slot
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	start := time.Now()
+	if err := l.waitToken(ctx); err != nil {
+		return nil, err
+	}
+
+	release := func() {}
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+			release = func() { <-l.inFlight }
+		case <-ctx.Done():
+			// The request is not sent so the token reserved for it is not used either
+			l.refundToken()
+			return nil, ctx.Err()
+		}
+	}
+
+	if wait := time.Since(start); wait > time.Millisecond {
+		slog.Debug("Throttled call to Zenoss", "wait", wait)
+		if l.observe != nil {
+			l.observe(wait)
+		}
+	}
+	return release, nil
+}
+
+// waitToken reserves a token from the bucket and waits until it is available
+func (l *limiter) waitToken(ctx context.Context) error {
+	if l.rate <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		// Hand back the reserved token as it was never used
+		l.refundToken()
+		return ctx.Err()
+	}
+}
+
+// refundToken hands back a token reserved by waitToken which was not used
+func (l *limiter) refundToken() {
+	if l.rate <= 0 {
+		return
+	}
+	l.mu.Lock()
+	l.tokens = math.Min(l.burst, l.tokens+1)
+	l.mu.Unlock()
+}
@@ -0,0 +1,94 @@
+package zenoss
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(setInfoDeviceResponse))
+	}))
+	defer server.Close()
+
+	var waited atomic.Int64
+	api.(*client).limiter = newLimiter(&config{
+		rateLimit: 20,
+		rateBurst: 1,
+		throttleObserver: func(d time.Duration) {
+			waited.Add(int64(d))
+		},
+	})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	assert.Greater(t, waited.Load(), int64(0))
+}
+
+func TestRateLimitCancelled(t *testing.T) {
+	l := newLimiter(&config{rateLimit: 0.1, rateBurst: 1})
+	_, err := l.acquire(context.Background())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestMaxInFlightCancelledRefundsToken(t *testing.T) {
+	l := newLimiter(&config{rateLimit: 1, rateBurst: 2, maxInFlight: 1})
+	release, err := l.acquire(context.Background())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	release()
+
+	// The token of the cancelled call is available so this call is not throttled for a second
+	start := time.Now()
+	release, err = l.acquire(context.Background())
+	assert.NoError(t, err)
+	release()
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestMaxInFlight(t *testing.T) {
+	var current, peak atomic.Int32
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		rw.Write([]byte(setInfoDeviceResponse))
+	}))
+	defer server.Close()
+	api.(*client).limiter = newLimiter(&config{maxInFlight: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300))
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+}
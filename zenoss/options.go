@@ -0,0 +1,182 @@
+package zenoss
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Option configures the client created by New
+type Option func(*config)
+
+type config struct {
+	httpClient            *http.Client
+	tlsConfig             *tls.Config
+	rootCAs               *x509.CertPool
+	insecureSkipTLSVerify bool
+	timeout               time.Duration
+	proxy                 func(*http.Request) (*url.URL, error)
+	auth                  Authenticator
+	monitor               string
+	maxResponseSize       int64
+	retry                 *RetryPolicy
+	rateLimit             float64
+	rateBurst             int
+	maxInFlight           int
+	throttleObserver      func(time.Duration)
+	jobPollInterval       time.Duration
+	jobTimeout            time.Duration
+}
+
+// WithHTTPClient uses the given http client for all calls. TLS and proxy options are ignored when set,
+// as they are expected to be configured on the client's transport.
+func WithHTTPClient(c *http.Client) Option {
+	return func(cfg *config) {
+		cfg.httpClient = c
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used when connecting to Zenoss
+func WithTLSConfig(tc *tls.Config) Option {
+	return func(cfg *config) {
+		cfg.tlsConfig = tc
+	}
+}
+
+// WithRootCAs sets the certificate authorities used to verify the Zenoss server certificate
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(cfg *config) {
+		cfg.rootCAs = pool
+	}
+}
+
+// WithInsecureSkipTLSVerify disables verification of the Zenoss server certificate
+func WithInsecureSkipTLSVerify(skip bool) Option {
+	return func(cfg *config) {
+		cfg.insecureSkipTLSVerify = skip
+	}
+}
+
+// WithTimeout sets the overall timeout of each HTTP request made to Zenoss
+func WithTimeout(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.timeout = d
+	}
+}
+
+// WithProxy sets the proxy function used by the transport, e.g. http.ProxyFromEnvironment or http.ProxyURL
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(cfg *config) {
+		cfg.proxy = proxy
+	}
+}
+
+// WithBasicAuth authenticates every request using the given username and password
+func WithBasicAuth(username, password string) Option {
+	return WithAuthenticator(NewBasicAuth(StaticCredential(username), StaticCredential(password)))
+}
+
+// WithAPIKey authenticates every request using a Zenoss Cloud API key. The base URI given to New
+// must include the path of the collection zone, e.g. https://tenant.zenoss.io/cz0
+func WithAPIKey(key string) Option {
+	return WithAuthenticator(NewAPIKeyAuth(StaticCredential(key)))
+}
+
+// WithSessionAuth logs in once using the Zenoss login form and authenticates requests using the session cookie,
+// avoiding Zenoss authenticating the user on every request
+func WithSessionAuth(username, password string) Option {
+	return WithAuthenticator(NewSessionAuth(StaticCredential(username), StaticCredential(password)))
+}
+
+// WithAuthenticator authenticates every request using the given authenticator
+func WithAuthenticator(auth Authenticator) Option {
+	return func(cfg *config) {
+		cfg.auth = auth
+	}
+}
+
+// WithMonitor sets the monitor reported on events created by AddEvent
+func WithMonitor(monitor string) Option {
+	return func(cfg *config) {
+		cfg.monitor = monitor
+	}
+}
+
+// WithMaxResponseSize limits the size in bytes of response bodies read from Zenoss, default is 64 MiB
+func WithMaxResponseSize(n int64) Option {
+	return func(cfg *config) {
+		cfg.maxResponseSize = n
+	}
+}
+
+// WithRetry retries failed calls according to the given policy. Only reads are retried unless
+// the policy enables RetryWrites.
+func WithRetry(policy RetryPolicy) Option {
+	return func(cfg *config) {
+		p := policy.withDefaults()
+		cfg.retry = &p
+	}
+}
+
+// WithRateLimit limits the client to the given number of requests per second, allowing bursts of up to burst requests
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(cfg *config) {
+		cfg.rateLimit = perSecond
+		cfg.rateBurst = burst
+	}
+}
+
+// WithMaxInFlight limits the number of concurrent requests made by the client
+func WithMaxInFlight(n int) Option {
+	return func(cfg *config) {
+		cfg.maxInFlight = n
+	}
+}
+
+// WithThrottleObserver registers a function called with the time a request waited on the rate limit
+// or concurrency cap, e.g. for exposing it as a metric
+func WithThrottleObserver(observe func(wait time.Duration)) Option {
+	return func(cfg *config) {
+		cfg.throttleObserver = observe
+	}
+}
+
+func (cfg *config) buildHTTPClient() *http.Client {
+	if cfg.httpClient != nil {
+		c := *cfg.httpClient
+		if cfg.timeout > 0 {
+			c.Timeout = cfg.timeout
+		}
+		return &c
+	}
+
+	tc := &tls.Config{} //#nosec G402
+	if cfg.tlsConfig != nil {
+		tc = cfg.tlsConfig.Clone()
+	}
+	if cfg.rootCAs != nil {
+		tc.RootCAs = cfg.rootCAs
+	}
+	if cfg.insecureSkipTLSVerify {
+		tc.InsecureSkipVerify = true //#nosec G402
+	}
+
+	return &http.Client{
+		Timeout: cfg.timeout,
+		Transport: &http.Transport{
+			Proxy:           cfg.proxy,
+			TLSClientConfig: tc,
+		},
+	}
+}
+
+// WithJobPolling sets how often Zenoss jobs are polled while waiting for them, default is every 2 seconds,
+// and how long to wait for a job before giving up, default is 10 minutes
+func WithJobPolling(interval, timeout time.Duration) Option {
+	return func(cfg *config) {
+		cfg.jobPollInterval = interval
+		cfg.jobTimeout = timeout
+	}
+}
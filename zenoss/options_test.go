@@ -0,0 +1,64 @@
+package zenoss
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientTrimsBasePath(t *testing.T) {
+	api, err := NewClient("https://zenoss.example.com/zport/dmd", "user", "pass", "monitor", false)
+	assert.NoError(t, err)
+
+	c := api.(*client)
+	assert.Equal(t, "https://zenoss.example.com", c.url)
+	assert.Equal(t, "monitor", c.monitor)
+
+	req, _ := http.NewRequest("POST", c.url, nil)
+	assert.NoError(t, c.auth.Authenticate(req))
+	user, pass, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "pass", pass)
+}
+
+func TestNewWithRootCAs(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		user, pass, ok := req.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "pass", pass)
+		rw.Write([]byte(setInfoDeviceResponse))
+	}))
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	api, err := New(server.URL, WithRootCAs(pool), WithBasicAuth("user", "pass"), WithTimeout(5*time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, api.(*client).client.Timeout)
+
+	err = api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300)
+	assert.NoError(t, err)
+}
+
+func TestNewWithHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(setInfoDeviceResponse))
+	}))
+	defer server.Close()
+
+	hc := server.Client()
+	api, err := New(server.URL, WithHTTPClient(hc), WithTimeout(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), hc.Timeout, "given client must not be modified")
+
+	err = api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,341 @@
+package zenoss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	// OrganizerGroups is the root of the groups
+	OrganizerGroups = "/Groups"
+
+	// OrganizerSystems is the root of the systems
+	OrganizerSystems = "/Systems"
+
+	// OrganizerLocations is the root of the locations
+	OrganizerLocations = "/Locations"
+
+	// OrganizerDeviceClasses is the root of the device classes
+	OrganizerDeviceClasses = "/Devices"
+)
+
+// OrganizerNode is an organizer, i.e. a group, system, location or device class, in the tree returned by ListOrganizers
+type OrganizerNode struct {
+	UID string `json:"uid"`
+
+	// Path is the path of the organizer including its root, e.g. /Groups/SLA/Plus
+	Path string `json:"-"`
+
+	Name        string `json:"-"`
+	DeviceCount int    `json:"-"`
+
+	Children []OrganizerNode `json:"children"`
+}
+
+func (n *OrganizerNode) UnmarshalJSON(data []byte) error {
+	type plain OrganizerNode
+	var node struct {
+		plain
+		Text json.RawMessage `json:"text"`
+	}
+	if err := json.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	*n = OrganizerNode(node.plain)
+	n.Path = strings.TrimPrefix(n.UID, "/zport/dmd")
+
+	// The text of a node is either its name or an object also holding the number of devices
+	var text struct {
+		Text  string `json:"text"`
+		Count int    `json:"count"`
+	}
+	if err := json.Unmarshal(node.Text, &text); err == nil {
+		n.Name, n.DeviceCount = text.Text, text.Count
+	} else {
+		_ = json.Unmarshal(node.Text, &n.Name)
+	}
+	if n.Name == "" {
+		n.Name = path.Base(n.Path)
+	}
+	return nil
+}
+
+type organizerTreeData struct {
+	ID string `json:"id"`
+}
+
+type organizerTreeResponse struct {
+	response
+	Result []OrganizerNode `json:"result"`
+}
+
+type organizerAddData struct {
+	Type        string `json:"type"`
+	ContextUID  string `json:"contextUid"`
+	ID          string `json:"id"`
+	Description string `json:"description,omitempty"`
+}
+
+type organizerAddResponse struct {
+	response
+	Result organizerAddResult `json:"result"`
+}
+
+type organizerAddResult struct {
+	result
+	NodeConfig OrganizerNode `json:"nodeConfig"`
+}
+
+type objectExistsData struct {
+	UID string `json:"uid"`
+}
+
+type objectExistsResponse struct {
+	response
+	Result objectExistsResult `json:"result"`
+}
+
+type objectExistsResult struct {
+	result
+	Exists bool `json:"exists"`
+}
+
+type organizerDeleteData struct {
+	UID string `json:"uid"`
+}
+
+type organizerMoveData struct {
+	TargetUID    string `json:"targetUid"`
+	OrganizerUID string `json:"organizerUid"`
+}
+
+type organizerMoveResponse struct {
+	response
+	Result organizerMoveResult `json:"result"`
+}
+
+type organizerMoveResult struct {
+	result
+	Data struct {
+		UID string `json:"uid"`
+	} `json:"data"`
+}
+
+// organizerUID returns the uid of an organizer given by its path, e.g. /Groups/SLA, or by its uid
+func organizerUID(organizer string) string {
+	organizer = strings.TrimSuffix(organizer, "/")
+	if strings.HasPrefix(organizer, "/zport/dmd/") {
+		return organizer
+	}
+	if !strings.HasPrefix(organizer, "/") {
+		organizer = "/" + organizer
+	}
+	return "/zport/dmd" + organizer
+}
+
+// rootedOrganizerUID returns the uid of an organizer below the given root, e.g. OrganizerGroups, given by its
+// path relative to the root, e.g. /SLA or SLA, by its path including the root, e.g. /Groups/SLA, or by its uid
+func rootedOrganizerUID(root, organizer string) string {
+	uid := organizerUID(organizer)
+	if strings.HasPrefix(organizer, "/zport/dmd/") {
+		return uid
+	}
+	if path := strings.TrimPrefix(uid, "/zport/dmd"); path == root || strings.HasPrefix(path, root+"/") {
+		return uid
+	}
+	return "/zport/dmd" + root + strings.TrimPrefix(uid, "/zport/dmd")
+}
+
+func (z *client) ListOrganizers(ctx context.Context, root string) (*OrganizerNode, error) {
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodGetTree,
+		Data:   []interface{}{organizerTreeData{ID: organizerUID(root)}},
+	}
+	var res organizerTreeResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list organizers: %w", err)
+	}
+
+	if len(res.Result) == 0 {
+		return nil, fmt.Errorf("organizer %s: %w", root, ErrNotFound)
+	}
+	return &res.Result[0], nil
+}
+
+func (z *client) CreateOrganizer(ctx context.Context, parentPath, name, description string) (*OrganizerNode, error) {
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodAddNode,
+		Data: []interface{}{
+			organizerAddData{
+				Type:        "organizer",
+				ContextUID:  organizerUID(parentPath),
+				ID:          name,
+				Description: description,
+			},
+		},
+	}
+	var res organizerAddResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create organizer: %w", err)
+	}
+
+	if !res.Result.Success {
+		return nil, fmt.Errorf("create organizer returned unsuccessful: %w", res.failure(res.Result.result))
+	}
+
+	return &res.Result.NodeConfig, nil
+}
+
+func (z *client) EnsureOrganizerPath(ctx context.Context, organizer string) error {
+	uid := organizerUID(organizer)
+	segments := strings.Split(strings.TrimPrefix(uid, "/zport/dmd/"), "/")
+	if len(segments) < 2 {
+		return nil
+	}
+
+	exists, err := z.objectExists(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("unable to ensure organizer %s: %w", organizer, err)
+	}
+	if exists {
+		return nil
+	}
+
+	// Parents of an existing organizer exist as well, so walk up to the deepest existing one, the root always
+	// exists, and create the missing organizers below it
+	missing := len(segments) - 1
+	for ; missing > 1; missing-- {
+		exists, err := z.objectExists(ctx, "/zport/dmd/"+strings.Join(segments[:missing], "/"))
+		if err != nil {
+			return fmt.Errorf("unable to ensure organizer %s: %w", organizer, err)
+		}
+		if exists {
+			break
+		}
+	}
+	for i := missing; i < len(segments); i++ {
+		_, err := z.CreateOrganizer(ctx, "/zport/dmd/"+strings.Join(segments[:i], "/"), segments[i], "")
+		if err != nil && !errors.Is(err, ErrConflict) {
+			return fmt.Errorf("unable to ensure organizer %s: %w", organizer, err)
+		}
+	}
+	return nil
+}
+
+func (z *client) objectExists(ctx context.Context, uid string) (bool, error) {
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodObjectExists,
+		Data:   []interface{}{objectExistsData{UID: uid}},
+	}
+	var res objectExistsResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return false, fmt.Errorf("unable to check if object exists: %w", err)
+	}
+
+	if !res.Result.Success {
+		err = res.failure(res.Result.result)
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("check if object exists returned unsuccessful: %w", err)
+	}
+
+	return res.Result.Exists, nil
+}
+
+func (z *client) DeleteOrganizer(ctx context.Context, organizer string) error {
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodDeleteNode,
+		Data:   []interface{}{organizerDeleteData{UID: organizerUID(organizer)}},
+	}
+	var res deviceBulkResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return fmt.Errorf("unable to delete organizer: %w", err)
+	}
+
+	if !res.Result.Success {
+		return fmt.Errorf("delete organizer returned unsuccessful: %w", res.failure(res.Result))
+	}
+
+	return nil
+}
+
+func (z *client) MoveOrganizer(ctx context.Context, organizer, targetParentPath string) (string, error) {
+	uid, target := organizerUID(organizer), organizerUID(targetParentPath)
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodMoveOrganizer,
+		Data: []interface{}{
+			organizerMoveData{
+				TargetUID:    target,
+				OrganizerUID: uid,
+			},
+		},
+	}
+	var res organizerMoveResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return "", fmt.Errorf("unable to move organizer: %w", err)
+	}
+
+	if !res.Result.Success {
+		return "", fmt.Errorf("move organizer returned unsuccessful: %w", res.failure(res.Result.result))
+	}
+
+	if res.Result.Data.UID == "" {
+		return target + "/" + path.Base(uid), nil
+	}
+	return res.Result.Data.UID, nil
+}
+
+func (z *client) AddDevicesToOrganizer(ctx context.Context, uids []string, organizer string) error {
+	if len(uids) == 0 {
+		return nil
+	}
+
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodMoveDevices,
+		Data: []interface{}{
+			deviceMoveData{
+				deviceSelection: deviceSelection{UIDs: uids},
+				Target:          organizerUID(organizer),
+			},
+		},
+	}
+	var res newJobsResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return fmt.Errorf("unable to add devices to organizer: %w", err)
+	}
+
+	if !res.Result.Success {
+		return fmt.Errorf("add devices to organizer returned unsuccessful: %w", res.failure(res.Result.result))
+	}
+
+	if job := z.newJob(res.Result.Jobs); job != nil {
+		if _, err := job.Wait(ctx); err != nil {
+			return fmt.Errorf("unable to add devices to organizer: %w", err)
+		}
+	}
+	return nil
+}
+
+func (z *client) RemoveDevicesFromOrganizer(ctx context.Context, uids []string, organizer string) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	return z.removeDevices(ctx, uids, DeleteModeRemoveFromOrganizer, organizerUID(organizer), false, nil)
+}
@@ -0,0 +1,184 @@
+package zenoss
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const getTreeResponse = `{
+	"uuid": "1",
+	"action": "DeviceRouter",
+	"result": [
+	  {
+		"uid": "/zport/dmd/Systems",
+		"id": ".zport.dmd.Systems",
+		"path": "Systems",
+		"text": {"text": "Systems", "count": 12, "description": "systems"},
+		"children": [
+		  {
+			"uid": "/zport/dmd/Systems/Netic",
+			"id": ".zport.dmd.Systems.Netic",
+			"path": "Systems/Netic",
+			"text": {"text": "Netic", "count": 12, "description": "systems"},
+			"children": [
+			  {
+				"uid": "/zport/dmd/Systems/Netic/Test",
+				"text": "Test",
+				"leaf": true,
+				"children": []
+			  }
+			]
+		  }
+		]
+	  }
+	],
+	"tid": 1,
+	"type": "rpc",
+	"method": "getTree"
+  }`
+
+func TestListOrganizers(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"DeviceRouter","method":"getTree","data":[{"id":"/zport/dmd/Systems"}],"tid":1}`, buf.String())
+		rw.Write([]byte(getTreeResponse))
+	}))
+	defer server.Close()
+
+	root, err := api.ListOrganizers(context.Background(), OrganizerSystems)
+	assert.NoError(t, err)
+	assert.Equal(t, "/Systems", root.Path)
+	assert.Equal(t, 12, root.DeviceCount)
+	if assert.Len(t, root.Children, 1) && assert.Len(t, root.Children[0].Children, 1) {
+		assert.Equal(t, "Netic", root.Children[0].Name)
+		assert.Equal(t, OrganizerNode{UID: "/zport/dmd/Systems/Netic/Test", Path: "/Systems/Netic/Test", Name: "Test", Children: []OrganizerNode{}}, root.Children[0].Children[0])
+	}
+}
+
+func TestCreateOrganizer(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"DeviceRouter","method":"addNode","data":[{"type":"organizer","contextUid":"/zport/dmd/Systems/Netic","id":"Customer","description":"Customer systems"}],"tid":1}`, buf.String())
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"nodeConfig": {"uid": "/zport/dmd/Systems/Netic/Customer", "text": {"text": "Customer", "count": 0}, "children": []}, "success": true}, "tid": 1, "type": "rpc", "method": "addNode"}`))
+	}))
+	defer server.Close()
+
+	node, err := api.CreateOrganizer(context.Background(), "/Systems/Netic", "Customer", "Customer systems")
+	assert.NoError(t, err)
+	assert.Equal(t, "/Systems/Netic/Customer", node.Path)
+}
+
+func TestCreateOrganizerConflict(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"msg": "The id \"Customer\" is invalid - it is already in use.", "success": false}, "tid": 1, "type": "rpc", "method": "addNode"}`))
+	}))
+	defer server.Close()
+
+	_, err := api.CreateOrganizer(context.Background(), "/Systems/Netic", "Customer", "")
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestDeleteAndMoveOrganizer(t *testing.T) {
+	bodies := []string{}
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		bodies = append(bodies, buf.String())
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"success": true}, "tid": 1, "type": "rpc", "method": "moveOrganizer"}`))
+	}))
+	defer server.Close()
+
+	assert.NoError(t, api.DeleteOrganizer(context.Background(), "/Groups/Old"))
+	uid, err := api.MoveOrganizer(context.Background(), "/Locations/DC4", "/Locations/Denmark/")
+	assert.NoError(t, err)
+	assert.Equal(t, "/zport/dmd/Locations/Denmark/DC4", uid)
+	assert.Equal(t, []string{
+		`{"action":"DeviceRouter","method":"deleteNode","data":[{"uid":"/zport/dmd/Groups/Old"}],"tid":1}`,
+		`{"action":"DeviceRouter","method":"moveOrganizer","data":[{"targetUid":"/zport/dmd/Locations/Denmark","organizerUid":"/zport/dmd/Locations/DC4"}],"tid":2}`,
+	}, bodies)
+}
+
+func TestAddAndRemoveDevicesFromOrganizer(t *testing.T) {
+	bodies := []string{}
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		bodies = append(bodies, buf.String())
+		if strings.Contains(buf.String(), "getDevices") {
+			rw.Write([]byte(readDeviceResponse))
+			return
+		}
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"success": true}, "tid": 1, "type": "rpc", "method": "moveDevices"}`))
+	}))
+	defer server.Close()
+
+	assert.NoError(t, api.AddDevicesToOrganizer(context.Background(), []string{"/zport/dmd/Devices/a"}, "/Groups/SLA/Plus"))
+	assert.NoError(t, api.RemoveDevicesFromOrganizer(context.Background(), []string{"/zport/dmd/Devices/a"}, "/Groups/SLA/Plus"))
+	assert.Equal(t, []string{
+		`{"action":"DeviceRouter","method":"moveDevices","data":[{"uids":["/zport/dmd/Devices/a"],"hashcheck":"","target":"/zport/dmd/Groups/SLA/Plus"}],"tid":1}`,
+		`{"action":"DeviceRouter","method":"getDevices","data":[{"uid":"/zport/dmd/Devices/a"}],"tid":2}`,
+		`{"action":"DeviceRouter","method":"removeDevices","data":[{"action":"remove","uids":["/zport/dmd/Devices/a"],"hashcheck":"1","uid":"/zport/dmd/Groups/SLA/Plus","deleteEvents":false}],"tid":3}`,
+	}, bodies)
+}
+
+func TestEnsureOrganizerPath(t *testing.T) {
+	bodies := []string{}
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		bodies = append(bodies, buf.String())
+		switch {
+		case strings.Contains(buf.String(), `"uid":"/zport/dmd/Systems/Netic"`):
+			rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"exists": true, "success": true}, "tid": 1, "type": "rpc", "method": "objectExists"}`))
+		case strings.Contains(buf.String(), "objectExists"):
+			rw.Write([]byte(`{"uuid": "2", "action": "DeviceRouter", "result": {"exists": false, "success": true}, "tid": 2, "type": "rpc", "method": "objectExists"}`))
+		case strings.Contains(buf.String(), `"id":"Customer"`):
+			// Created concurrently by someone else
+			rw.Write([]byte(`{"uuid": "3", "action": "DeviceRouter", "result": {"msg": "The id \"Customer\" is invalid - it is already in use.", "success": false}, "tid": 3, "type": "rpc", "method": "addNode"}`))
+		default:
+			rw.Write([]byte(`{"uuid": "4", "action": "DeviceRouter", "result": {"nodeConfig": {"uid": "/zport/dmd/Systems/Netic/Customer/prod"}, "success": true}, "tid": 4, "type": "rpc", "method": "addNode"}`))
+		}
+	}))
+	defer server.Close()
+
+	assert.NoError(t, api.EnsureOrganizerPath(context.Background(), "/Systems/Netic/Customer/prod"))
+	assert.Equal(t, []string{
+		`{"action":"DeviceRouter","method":"objectExists","data":[{"uid":"/zport/dmd/Systems/Netic/Customer/prod"}],"tid":1}`,
+		`{"action":"DeviceRouter","method":"objectExists","data":[{"uid":"/zport/dmd/Systems/Netic/Customer"}],"tid":2}`,
+		`{"action":"DeviceRouter","method":"objectExists","data":[{"uid":"/zport/dmd/Systems/Netic"}],"tid":3}`,
+		`{"action":"DeviceRouter","method":"addNode","data":[{"type":"organizer","contextUid":"/zport/dmd/Systems/Netic","id":"Customer"}],"tid":4}`,
+		`{"action":"DeviceRouter","method":"addNode","data":[{"type":"organizer","contextUid":"/zport/dmd/Systems/Netic/Customer","id":"prod"}],"tid":5}`,
+	}, bodies)
+}
+
+func TestEnsureOrganizerPathExisting(t *testing.T) {
+	bodies := []string{}
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		bodies = append(bodies, buf.String())
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"exists": true, "success": true}, "tid": 1, "type": "rpc", "method": "objectExists"}`))
+	}))
+	defer server.Close()
+
+	assert.NoError(t, api.EnsureOrganizerPath(context.Background(), "/Systems/Netic/Customer/prod"))
+	assert.Equal(t, []string{
+		`{"action":"DeviceRouter","method":"objectExists","data":[{"uid":"/zport/dmd/Systems/Netic/Customer/prod"}],"tid":1}`,
+	}, bodies)
+}
+
+func TestRootedOrganizerUID(t *testing.T) {
+	for _, organizer := range []string{"SLA/Plus", "/SLA/Plus", "/Groups/SLA/Plus", "Groups/SLA/Plus/", "/zport/dmd/Groups/SLA/Plus"} {
+		assert.Equal(t, "/zport/dmd/Groups/SLA/Plus", rootedOrganizerUID(OrganizerGroups, organizer), organizer)
+	}
+	assert.Equal(t, "/zport/dmd/Groups", rootedOrganizerUID(OrganizerGroups, "/Groups"))
+	assert.Equal(t, "/zport/dmd/Locations/Groups", rootedOrganizerUID(OrganizerLocations, "/Groups"))
+	assert.Equal(t, "/zport/dmd/Systems/Netic", organizerUID("Systems/Netic"))
+}
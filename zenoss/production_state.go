@@ -0,0 +1,112 @@
+package zenoss
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ProductionState is the production state of a device
+type ProductionState int
+
+const (
+	// ProductionStateProduction is the state of devices in production
+	ProductionStateProduction = ProductionState(1000)
+
+	// ProductionStatePreProduction is the state of devices about to go into production
+	ProductionStatePreProduction = ProductionState(500)
+
+	// ProductionStateTest is the state of test devices
+	ProductionStateTest = ProductionState(400)
+
+	// ProductionStateMaintenance is the state of devices under maintenance
+	ProductionStateMaintenance = ProductionState(300)
+
+	// ProductionStateDecommissioned is the state of devices taken out of service
+	ProductionStateDecommissioned = ProductionState(-1)
+)
+
+// defaultProductionStates are the production states of a default Zenoss installation
+var defaultProductionStates = ProductionStateNames{
+	ProductionStateProduction:     "Production",
+	ProductionStatePreProduction:  "Pre-Production",
+	ProductionStateTest:           "Test",
+	ProductionStateMaintenance:    "Maintenance",
+	ProductionStateDecommissioned: "Decommissioned",
+}
+
+// String returns the name of the production state in a default Zenoss installation or its value if unknown
+func (p ProductionState) String() string {
+	return defaultProductionStates.Name(p)
+}
+
+// ParseProductionState parses the name of a production state of a default Zenoss installation or a numeric value
+func ParseProductionState(s string) (ProductionState, error) {
+	return defaultProductionStates.Parse(s)
+}
+
+// ProductionStateNames maps production states to their names as configured in a Zenoss installation
+type ProductionStateNames map[ProductionState]string
+
+// Name returns the name of the production state or its value if unknown
+func (n ProductionStateNames) Name(p ProductionState) string {
+	if name, ok := n[p]; ok {
+		return name
+	}
+	return strconv.Itoa(int(p))
+}
+
+// Parse returns the production state with the given name, ignoring case and dashes, or a numeric value
+func (n ProductionStateNames) Parse(s string) (ProductionState, error) {
+	key := normalizeProductionState(s)
+	for p, name := range n {
+		if normalizeProductionState(name) == key {
+			return p, nil
+		}
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+		return ProductionState(v), nil
+	}
+	return 0, fmt.Errorf("unknown production state %q", s)
+}
+
+func normalizeProductionState(s string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(s))
+}
+
+type productionStatesResponse struct {
+	response
+	Result productionStatesResult `json:"result"`
+}
+
+type productionStatesResult struct {
+	result
+	Data []struct {
+		Name  string          `json:"name"`
+		Value ProductionState `json:"value"`
+	} `json:"data"`
+}
+
+func (z *client) ProductionStates(ctx context.Context) (ProductionStateNames, error) {
+	req := request{
+		Action: actionDeviceRoute,
+		Method: methodGetProductionStates,
+		Data:   []interface{}{struct{}{}},
+	}
+	var res productionStatesResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read production states: %w", err)
+	}
+
+	if !res.Result.Success {
+		return nil, fmt.Errorf("read production states returned unsuccessful: %w", res.failure(res.Result.result))
+	}
+
+	names := make(ProductionStateNames, len(res.Result.Data))
+	for _, s := range res.Result.Data {
+		names[s.Value] = s.Name
+	}
+	return names, nil
+}
@@ -0,0 +1,57 @@
+package zenoss
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductionStateString(t *testing.T) {
+	assert.Equal(t, "Production", ProductionStateProduction.String())
+	assert.Equal(t, "Pre-Production", ProductionStatePreProduction.String())
+	assert.Equal(t, "Decommissioned", ProductionStateDecommissioned.String())
+	assert.Equal(t, "42", ProductionState(42).String())
+}
+
+func TestParseProductionState(t *testing.T) {
+	for s, want := range map[string]ProductionState{
+		"Production":     ProductionStateProduction,
+		"preproduction":  ProductionStatePreProduction,
+		"Pre-Production": ProductionStatePreProduction,
+		"MAINTENANCE":    ProductionStateMaintenance,
+		"test":           ProductionStateTest,
+		"-1":             ProductionStateDecommissioned,
+		"42":             ProductionState(42),
+	} {
+		p, err := ParseProductionState(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, want, p, s)
+	}
+
+	_, err := ParseProductionState("retired")
+	assert.ErrorContains(t, err, `unknown production state "retired"`)
+}
+
+func TestProductionStates(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/zport/dmd/device_router", req.URL.Path)
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		assert.Equal(t, `{"action":"DeviceRouter","method":"getProductionStates","data":[{}],"tid":1}`, buf.String())
+		rw.Write([]byte(`{"uuid": "1", "action": "DeviceRouter", "result": {"data": [{"name": "Production", "value": 1000}, {"name": "Staging", "value": 600}, {"name": "Maintenance", "value": 300}], "success": true}, "tid": 1, "type": "rpc", "method": "getProductionStates"}`))
+	}))
+	defer server.Close()
+
+	names, err := api.ProductionStates(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "Staging", names.Name(600))
+	assert.Equal(t, "500", names.Name(ProductionStatePreProduction))
+
+	p, err := names.Parse("staging")
+	assert.NoError(t, err)
+	assert.Equal(t, ProductionState(600), p)
+}
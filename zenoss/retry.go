@@ -0,0 +1,122 @@
+package zenoss
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBaseDelay   = 500 * time.Millisecond
+	defaultRetryMaxDelay    = 10 * time.Second
+)
+
+// idempotentMethods are router methods which are safe to retry without opting in to RetryWrites
+var idempotentMethods = map[method]bool{
+	methodGetDevices:          true,
+	methodGetInfo:             true,
+	methodGetProductionStates: true,
+	methodJobDetail:           true,
+	methodGetTree:             true,
+	methodObjectExists:        true,
+	methodGetCustomProperties: true,
+}
+
+// RetryPolicy controls how failed calls to Zenoss are retried. Connection errors and responses with
+// a retryable status code are retried with exponential backoff.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one, default is 3
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry, doubled on each following retry, default is 500ms
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between attempts, default is 10s
+	MaxDelay time.Duration
+
+	// Jitter is the fraction, between 0 and 1, by which the delay is randomly varied
+	Jitter float64
+
+	// RetryableStatus are the HTTP status codes retried, default is 429, 502, 503 and 504
+	RetryableStatus []int
+
+	// RetryWrites enables retries of calls changing data, e.g. add_event and setInfo. Such a call
+	// may be applied twice if the response of a successful attempt is lost.
+	RetryWrites bool
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaultRetryBaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultRetryMaxDelay
+	}
+	p.Jitter = math.Min(math.Max(p.Jitter, 0), 1)
+	if p.RetryableStatus == nil {
+		p.RetryableStatus = []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+	return p
+}
+
+// retryable reports whether the failed attempt should be retried
+func (p *RetryPolicy) retryable(err error) bool {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		// Connection errors and errors reading the response
+		var netErr net.Error
+		return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+	}
+	for _, s := range p.RetryableStatus {
+		if s == httpErr.StatusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the time to wait after the given failed attempt, honouring Retry-After sent by the server
+func (p *RetryPolicy) delay(attempt int, err error) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d)) //#nosec G404
+		d = min(d, p.MaxDelay)
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.retryAfter > d {
+		d = httpErr.retryAfter
+	}
+	return d
+}
+
+// parseRetryAfter parses the value of a Retry-After header given either as seconds or a HTTP date
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
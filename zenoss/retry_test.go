@@ -0,0 +1,105 @@
+package zenoss
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryRead(t *testing.T) {
+	calls := 0
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls < 3 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		rw.Write(batchResponse(t, buf.String(), readDeviceResponse, readDeviceLockingResponse))
+	}))
+	defer server.Close()
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}.withDefaults()
+	api.(*client).retry = &policy
+
+	device, err := api.ReadDevice(context.Background(), "/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk")
+	assert.NoError(t, err)
+	if assert.NotNil(t, device) {
+		assert.Equal(t, "oaas1.k8s.jysk.netic.dk", device.Name)
+	}
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetryWrites(t *testing.T) {
+	calls := 0
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls == 1 {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		rw.Write([]byte(setInfoDeviceResponse))
+	}))
+	defer server.Close()
+
+	policy := RetryPolicy{BaseDelay: time.Millisecond}.withDefaults()
+	api.(*client).retry = &policy
+	err := api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	policy.RetryWrites = true
+	err = api.UpdateDeviceProductionState(context.Background(), "/zport/dmd/Devices/device", 300)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetryCancelled(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Retry-After", "60")
+		rw.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	policy := RetryPolicy{}.withDefaults()
+	api.(*client).retry = &policy
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := api.ReadDevice(ctx, "/zport/dmd/Devices/device")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 3 * time.Second}.withDefaults()
+	assert.Equal(t, time.Second, p.delay(1, nil))
+	assert.Equal(t, 2*time.Second, p.delay(2, nil))
+	assert.Equal(t, 3*time.Second, p.delay(3, nil))
+	assert.Equal(t, 3*time.Second, p.delay(100, nil))
+	assert.Equal(t, 5*time.Second, p.delay(1, &HTTPError{retryAfter: 5 * time.Second}))
+
+	p.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		d := p.delay(1, nil)
+		assert.GreaterOrEqual(t, d, 500*time.Millisecond)
+		assert.LessOrEqual(t, d, 1500*time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		d := p.delay(100, nil)
+		assert.GreaterOrEqual(t, d, 1500*time.Millisecond)
+		assert.LessOrEqual(t, d, 3*time.Second)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 120*time.Second, parseRetryAfter("120"))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(""))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("invalid"))
+	d := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.InDelta(t, time.Minute, d, float64(2*time.Second))
+}
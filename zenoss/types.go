@@ -1,6 +1,14 @@
 package zenoss
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
 
 // Severity defines event severity
 type Severity string
@@ -25,21 +33,136 @@ const (
 	SeverityClear = Severity("Clear")
 )
 
+// Device is a device as listed by Zenoss
 type Device struct {
-	UID             string `json:"uid"`
-	Name            string `json:"name"`
-	ProductionState int    `json:"productionState"`
+	UID             string          `json:"uid"`
+	Name            string          `json:"name"`
+	ProductionState ProductionState `json:"productionState"`
+	Priority        int             `json:"priority"`
+	IPAddressString string          `json:"ipAddressString"`
+
+	// IPAddress is the manage IP as the integer sent by Zenoss, which exceeds 64 bits for IPv6 addresses
+	IPAddress json.Number `json:"ipAddress"`
+
+	Collector    string `json:"collector"`
+	SerialNumber string `json:"serialNumber"`
+	TagNumber    string `json:"tagNumber"`
+	PythonClass  string `json:"pythonClass"`
+
+	HWManufacturer *Organizer `json:"hwManufacturer"`
+	HWModel        *Organizer `json:"hwModel"`
+	OSManufacturer *Organizer `json:"osManufacturer"`
+	OSModel        *Organizer `json:"osModel"`
+
+	Systems  []Organizer `json:"systems"`
+	Groups   []Organizer `json:"groups"`
+	Location *Organizer  `json:"location"`
+
+	Events EventCounts `json:"events"`
+
+	// Locking is the lock state of the device, read separately by ReadDevice as getDevices does not return it.
+	// It is nil if not read or if reading it failed.
+	Locking *Locking `json:"locking"`
+
+	// Extra holds the fields returned by Zenoss which are not covered by the fields above
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+func (d *Device) UnmarshalJSON(data []byte) error {
+	type plain Device
+	extra, err := unmarshalWithExtra(data, (*plain)(d))
+	if err != nil {
+		return err
+	}
+	d.Extra = extra
+	return nil
+}
+
+// Locking is the lock state of a device
+type Locking struct {
+	// Updates is true if the device is locked from updates, which implies it is locked from deletion
+	Updates bool `json:"updates"`
+
+	// Deletion is true if the device is locked from deletion
+	Deletion bool `json:"deletion"`
+
+	// SendEvent is true if an event is sent when a change is blocked by the lock
+	SendEvent bool `json:"events"`
+}
+
+// LockOptions selects what devices are locked from
+type LockOptions struct {
+	// Updates locks devices from updates and deletion
+	Updates bool
+
+	// Deletion locks devices from deletion
+	Deletion bool
+
+	// SendEvent makes Zenoss send an event when a change is blocked by the lock
+	SendEvent bool
+}
+
+// DeleteMode selects whether DeleteDevices deletes devices or removes them from an organizer
+type DeleteMode string
+
+const (
+	// DeleteModeDelete deletes the devices from Zenoss
+	DeleteModeDelete = DeleteMode("delete")
+
+	// DeleteModeRemoveFromOrganizer removes the devices from a group, system or location without deleting them
+	DeleteModeRemoveFromOrganizer = DeleteMode("remove")
+)
+
+// DeleteOptions controls how DeleteDevices deletes devices
+type DeleteOptions struct {
+	// DeleteEvents deletes the events of the devices
+	DeleteEvents bool
+
+	// DeletePerf deletes the performance data of the devices
+	DeletePerf bool
+
+	// Mode selects whether the devices are deleted or removed from an organizer, default is DeleteModeDelete
+	Mode DeleteMode
+
+	// Organizer is the group, system or location to remove the devices from with DeleteModeRemoveFromOrganizer,
+	// e.g. /Groups/SLA/Plus
+	Organizer string
+}
+
+// Organizer references an organizer such as a group, system or location, or another object like a manufacturer.
+// Only the fields returned by Zenoss for the given reference are set.
+type Organizer struct {
+	UID  string `json:"uid"`
+	Path string `json:"path,omitempty"`
+	UUID string `json:"uuid,omitempty"`
+	Name string `json:"name"`
+}
+
+// EventCounts holds the number of events on a device per severity
+type EventCounts struct {
+	Critical EventCount `json:"critical"`
+	Error    EventCount `json:"error"`
+	Warning  EventCount `json:"warning"`
+	Info     EventCount `json:"info"`
+	Debug    EventCount `json:"debug"`
+	Clear    EventCount `json:"clear"`
+}
+
+// EventCount holds the number of events with a given severity
+type EventCount struct {
+	Count             int `json:"count"`
+	AcknowledgedCount int `json:"acknowledged_count"`
 }
 
 type NewDevice struct {
-	Name            string   `json:"deviceName"`
-	Class           string   `json:"deviceClass"`
-	Collector       string   `json:"collector"`
-	Model           bool     `json:"model"`
-	ProductionState int      `json:"productionState"`
-	GroupPaths      []string `json:"groupPaths"`
-	SystemPaths     []string `json:"systemPaths"`
-	LocationPath    string   `json:"locationPath,omitempty"`
+	Name            string          `json:"deviceName"`
+	Class           string          `json:"deviceClass"`
+	Collector       string          `json:"collector"`
+	Model           bool            `json:"model"`
+	ProductionState ProductionState `json:"productionState"`
+	GroupPaths      []string        `json:"groupPaths"`
+	SystemPaths     []string        `json:"systemPaths"`
+	LocationPath    string          `json:"locationPath,omitempty"`
 }
 
 type CustomProperty struct {
@@ -49,14 +172,190 @@ type CustomProperty struct {
 	IsLocal int    `json:"islocal"`
 }
 
+// SortDirection is the direction in which devices are sorted
+type SortDirection string
+
+const (
+	// SortAscending sorts in ascending order
+	SortAscending = SortDirection("ASC")
+
+	// SortDescending sorts in descending order
+	SortDescending = SortDirection("DESC")
+)
+
+// DeviceQuery filters, sorts and pages devices. Filters left empty are not applied.
+type DeviceQuery struct {
+	// UID of the organizer to list devices below, default is all devices
+	UID string
+
+	Name            string
+	IPAddress       string
+	DeviceClass     string
+	ProductionState []ProductionState
+	Group           string
+	System          string
+	Location        string
+	Collector       string
+
+	// Start is the offset of the first device returned
+	Start int
+
+	// Limit is the maximum number of devices returned, Zenoss defaults to 50
+	Limit int
+
+	// Sort is the name of the field to sort by, Zenoss defaults to name
+	Sort string
+
+	// Dir is the direction to sort in, Zenoss defaults to ascending
+	Dir SortDirection
+}
+
+// DeviceList is a page of devices matching a query
+type DeviceList struct {
+	Devices []Device
+
+	// TotalCount is the number of devices matching the query across all pages
+	TotalCount int
+
+	// Hash identifies the state of the devices matching the query, it changes when they are modified
+	Hash string
+}
+
+// DeviceInfo holds the details of a device as shown on its overview page in Zenoss
+type DeviceInfo struct {
+	UID             string          `json:"uid"`
+	ID              string          `json:"id"`
+	Name            string          `json:"name"`
+	IPAddressString string          `json:"ipAddressString"`
+	ProductionState ProductionState `json:"productionState"`
+	Priority        int             `json:"priority"`
+	Collector       string          `json:"collector"`
+	Comments        string          `json:"comments"`
+	Uptime          string          `json:"uptime"`
+	TagNumber       string          `json:"tagNumber"`
+	SerialNumber    string          `json:"serialNumber"`
+	RackSlot        string          `json:"rackSlot"`
+	Links           string          `json:"links"`
+
+	FirstSeen     Timestamp `json:"firstSeen"`
+	LastChanged   Timestamp `json:"lastChanged"`
+	LastCollected Timestamp `json:"lastCollected"`
+
+	SNMPCommunity string `json:"snmpCommunity"`
+	SNMPVersion   string `json:"snmpVersion"`
+	SNMPSysName   string `json:"snmpSysName"`
+	SNMPLocation  string `json:"snmpLocation"`
+	SNMPContact   string `json:"snmpContact"`
+	SNMPDescr     string `json:"snmpDescr"`
+
+	DeviceClass    *Organizer  `json:"deviceClass"`
+	Location       *Organizer  `json:"location"`
+	Groups         []Organizer `json:"groups"`
+	Systems        []Organizer `json:"systems"`
+	HWManufacturer *Organizer  `json:"hwManufacturer"`
+	HWModel        *Organizer  `json:"hwModel"`
+	OSManufacturer *Organizer  `json:"osManufacturer"`
+	OSModel        *Organizer  `json:"osModel"`
+
+	Locking *Locking `json:"locking"`
+
+	// Extra holds the fields returned by Zenoss which are not covered by the fields above, e.g. zProperties
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+func (d *DeviceInfo) UnmarshalJSON(data []byte) error {
+	type plain DeviceInfo
+	extra, err := unmarshalWithExtra(data, (*plain)(d))
+	if err != nil {
+		return err
+	}
+	d.Extra = extra
+	return nil
+}
+
+// DeviceUpdate holds the attributes of a device to update. Only fields which are set are sent to Zenoss,
+// so attributes managed elsewhere are left untouched. Set a field to an empty value to clear it.
+type DeviceUpdate struct {
+	ProductionState *ProductionState `json:"productionState,omitempty"`
+	Title           *string          `json:"name,omitempty"`
+	Comments        *string          `json:"comments,omitempty"`
+	Priority        *int             `json:"priority,omitempty"`
+	SerialNumber    *string          `json:"serialNumber,omitempty"`
+	TagNumber       *string          `json:"tagNumber,omitempty"`
+	RackSlot        *string          `json:"rackSlot,omitempty"`
+
+	SNMPCommunity *string `json:"snmpCommunity,omitempty"`
+	SNMPVersion   *string `json:"snmpVersion,omitempty"`
+	SNMPLocation  *string `json:"snmpLocation,omitempty"`
+	SNMPContact   *string `json:"snmpContact,omitempty"`
+
+	HWManufacturer *string `json:"hwManufacturer,omitempty"`
+	HWProductName  *string `json:"hwProductName,omitempty"`
+	OSManufacturer *string `json:"osManufacturer,omitempty"`
+	OSProductName  *string `json:"osProductName,omitempty"`
+}
+
+// Ptr returns a pointer to the given value, e.g. for setting fields of DeviceUpdate
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// Timestamp is a point in time returned by Zenoss either as seconds since epoch or as a formatted string.
+// It is the zero time if Zenoss returns no time, e.g. "Not Modeled" for a device never modeled.
+type Timestamp struct {
+	time.Time
+}
+
+// timestampLayouts are the layouts Zenoss uses when formatting timestamps as strings
+var timestampLayouts = []string{
+	"2006/01/02 15:04:05.000",
+	"2006/01/02 15:04:05",
+	time.RFC3339Nano,
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	t.Time = time.Time{}
+	switch v := v.(type) {
+	case float64:
+		if v > 0 {
+			sec, frac := math.Modf(v)
+			t.Time = time.Unix(int64(sec), int64(frac*1e9))
+		}
+	case string:
+		for _, layout := range timestampLayouts {
+			if ts, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+				t.Time = ts
+				break
+			}
+		}
+	}
+	return nil
+}
+
 type deviceReadData struct {
 	UID    string            `json:"uid,omitempty"`
 	Params *deviceReadParams `json:"params,omitempty"`
+	Start  int               `json:"start,omitempty"`
+	Limit  int               `json:"limit,omitempty"`
+	Sort   string            `json:"sort,omitempty"`
+	Dir    SortDirection     `json:"dir,omitempty"`
 }
 
-// Can be one of the following: name, ipAddress, deviceClass, or productionState
+// Filters supported by getDevices
 type deviceReadParams struct {
-	Name string `json:"name,omitempty"`
+	Name            string            `json:"name,omitempty"`
+	IPAddress       string            `json:"ipAddress,omitempty"`
+	DeviceClass     string            `json:"deviceClass,omitempty"`
+	ProductionState []ProductionState `json:"productionState,omitempty"`
+	Groups          string            `json:"groups,omitempty"`
+	Systems         string            `json:"systems,omitempty"`
+	Location        string            `json:"location,omitempty"`
+	Collector       string            `json:"collector,omitempty"`
 }
 
 type action string
@@ -78,14 +377,58 @@ type response struct {
 	Action action `json:"action"`
 	Method method `json:"method"`
 	Tid    int    `json:"tid"`
+
+	// Type is "rpc" for results and "exception" when the router call raised an error
+	Type    string `json:"type"`
+	Message string `json:"message,omitempty"`
+	Where   string `json:"where,omitempty"`
+
+	status int
 }
 
 func (r response) String() string {
 	return fmt.Sprintf("{uuid=%s,action=%s,method=%s,tid=%d}", r.UUID, r.Action, r.Method, r.Tid)
 }
 
+func (r *response) envelope() *response {
+	return r
+}
+
+// exception returns the error carried by an Ext.Direct exception response, or nil
+func (r *response) exception() error {
+	if r.Type != "exception" {
+		return nil
+	}
+	return &APIError{
+		Router:     string(r.Action),
+		Method:     string(r.Method),
+		Tid:        r.Tid,
+		Msg:        r.Message,
+		HTTPStatus: r.status,
+		Err:        classify(r.status, r.Message),
+	}
+}
+
+// failure returns the error for an unsuccessful result of the router call
+func (r *response) failure(res result) error {
+	return &APIError{
+		Router:     string(r.Action),
+		Method:     string(r.Method),
+		Tid:        r.Tid,
+		Msg:        res.Msg,
+		HTTPStatus: r.status,
+		Err:        classify(r.status, res.Msg),
+	}
+}
+
+// enveloped is implemented by all response types through the embedded response
+type enveloped interface {
+	envelope() *response
+}
+
 type result struct {
-	Success bool `json:"success"`
+	Success bool   `json:"success"`
+	Msg     string `json:"msg,omitempty"`
 }
 
 type addEventResponse struct {
@@ -105,25 +448,123 @@ type deviceReadResult struct {
 	Devices []Device `json:"devices"`
 }
 
-type deviceAddResponse struct {
+// deviceSelection selects devices either by uids or by ranges of the devices matching a query
+type deviceSelection struct {
+	UIDs      []string          `json:"uids"`
+	Hashcheck string            `json:"hashcheck"`
+	UID       string            `json:"uid,omitempty"`
+	Ranges    [][2]int          `json:"ranges,omitempty"`
+	Params    *deviceReadParams `json:"params,omitempty"`
+	Sort      string            `json:"sort,omitempty"`
+	Dir       SortDirection     `json:"dir,omitempty"`
+}
+
+type deviceMoveData struct {
+	deviceSelection
+	Target string `json:"target"`
+}
+
+type deviceRenameData struct {
+	UID          string `json:"uid"`
+	NewID        string `json:"newId"`
+	RetainGraphs bool   `json:"retainGraphs"`
+}
+
+type deviceRenameResponse struct {
 	response
-	Result deviceAddResult `json:"result"`
+	Result deviceRenameResult `json:"result"`
 }
 
-type deviceAddResult struct {
+type deviceRenameResult struct {
 	result
-	Jobs []deviceAddJob `json:"new_jobs"`
+	UID string `json:"uid"`
+}
+
+type deviceResetIPData struct {
+	deviceSelection
+	IP string `json:"ip"`
+}
+
+type deviceManageIPData struct {
+	UID string `json:"uid"`
+	IP  string `json:"ip"`
 }
 
-type deviceAddJob struct {
-	UUID string `json:"uuid"`
+type deviceRemodelData struct {
+	DeviceUID string `json:"deviceUid"`
+}
+
+type deviceRemodelResponse struct {
+	response
+	Result deviceRemodelResult `json:"result"`
+}
+
+type deviceRemodelResult struct {
+	result
+	JobID string `json:"jobId"`
+}
+
+type deviceLockData struct {
+	deviceSelection
+	Updates   bool `json:"updates"`
+	Deletion  bool `json:"deletion"`
+	SendEvent bool `json:"sendEvent"`
+}
+
+type deviceProductionStateData struct {
+	deviceSelection
+	ProdState ProductionState `json:"prodState"`
+}
+
+type devicePriorityData struct {
+	deviceSelection
+	Priority int `json:"priority"`
+}
+
+type deviceBulkResponse struct {
+	response
+	Result result `json:"result"`
+}
+
+type deviceInfoData struct {
+	UID  string   `json:"uid"`
+	Keys []string `json:"keys,omitempty"`
+}
+
+type deviceInfoResponse struct {
+	response
+	Result deviceInfoResult `json:"result"`
+}
+
+type deviceInfoResult struct {
+	result
+	Data DeviceInfo `json:"data"`
+}
+
+// newJobsResponse is the response of router calls starting jobs, e.g. addDevice
+type newJobsResponse struct {
+	response
+	Result newJobsResult `json:"result"`
+}
+
+type newJobsResult struct {
+	result
+	Jobs []jobRef `json:"new_jobs"`
+}
+
+// jobRef is a job started by a router call as listed in new_jobs
+type jobRef struct {
+	UUID        string `json:"uuid"`
+	Description string `json:"description"`
 }
 
 type deviceRemoveData struct {
-	Action       string   `json:"action"`
-	UIDs         []string `json:"uids"`
-	Hashcheck    string   `json:"hashcheck"`
-	DeleteEvents bool     `json:"deleteEvents"`
+	Action       DeleteMode `json:"action"`
+	UIDs         []string   `json:"uids"`
+	Hashcheck    string     `json:"hashcheck"`
+	UID          string     `json:"uid,omitempty"`
+	DeleteEvents bool       `json:"deleteEvents"`
+	DeletePerf   *bool      `json:"deletePerf,omitempty"`
 }
 
 type deviceRemoveResponse struct {
@@ -132,8 +573,8 @@ type deviceRemoveResponse struct {
 }
 
 type deviceSetInfoData struct {
-	UID             string `json:"uid"`
-	ProductionState int    `json:"productionState"`
+	UID string `json:"uid"`
+	DeviceUpdate
 }
 
 type deviceSetInfoResponse struct {
@@ -181,3 +622,58 @@ type customPropertyUpdateResponse struct {
 	response
 	Result result `json:"result"`
 }
+
+// jsonFieldCache holds the JSON field names of struct types decoded by unmarshalWithExtra
+var jsonFieldCache sync.Map
+
+// unmarshalWithExtra decodes data into v, a pointer to a struct, and returns the fields of data
+// not known by the struct or nil if there are none
+func unmarshalWithExtra(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for name := range jsonFields(reflect.TypeOf(v).Elem()) {
+		delete(all, name)
+	}
+	if len(all) == 0 {
+		return nil, nil
+	}
+	return all, nil
+}
+
+// jsonFields returns the names of the JSON fields of the struct type including embedded structs
+func jsonFields(t reflect.Type) map[string]bool {
+	if f, ok := jsonFieldCache.Load(t); ok {
+		return f.(map[string]bool)
+	}
+
+	fields := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for n := range jsonFields(f.Type) {
+				fields[n] = true
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = true
+	}
+	jsonFieldCache.Store(t, fields)
+	return fields
+}
@@ -3,11 +3,11 @@ package zenoss
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 	"net/url"
 	"strings"
@@ -20,17 +20,113 @@ type Client interface {
 	// AddEvent to component on device in Zenoss
 	AddEvent(ctx context.Context, summary, message, device, component string, severity Severity, evClass, evKey string, extraData map[string]string) error
 
-	// ReadDevice returns information on the given device uid
+	// ReadDevice returns information on the given device uid including its lock state, Locking is nil if the
+	// lock state could not be read
 	ReadDevice(ctx context.Context, uid string) (*Device, error)
 
+	// ListDevices returns a page of the devices matching the query
+	ListDevices(ctx context.Context, query DeviceQuery) (*DeviceList, error)
+
+	// GetDeviceInfo returns the details of the given device uid, limited to the given keys if any
+	GetDeviceInfo(ctx context.Context, uid string, keys ...string) (*DeviceInfo, error)
+
+	// Devices returns an iterator over all devices matching the query, up to the limit of the query if set, fetching them page by page
+	Devices(ctx context.Context, query DeviceQuery) *DeviceIterator
+
 	// CreateDevice creates new in Zenoss
 	CreateDevice(ctx context.Context, dev NewDevice) (*Device, error)
 
+	// CreateDeviceAsync starts creating a device in Zenoss returning the job doing so without waiting for it,
+	// an error is returned if Zenoss did not start a job
+	CreateDeviceAsync(ctx context.Context, dev NewDevice) (*Job, error)
+
+	// EnsureDevice creates the device unless a device with the same name exists, first creating any missing
+	// groups, systems, location and device class of the device
+	EnsureDevice(ctx context.Context, dev NewDevice) (*Device, error)
+
 	// DeleteDevice deletes the device with the given uid
 	DeleteDevice(ctx context.Context, uid string) error
 
+	// DeleteDevices deletes the given device uids, or removes them from an organizer, according to the options
+	DeleteDevices(ctx context.Context, uids []string, opts DeleteOptions) error
+
 	// UpdateDeviceProductionState updates only the production state of the given device uid
-	UpdateDeviceProductionState(ctx context.Context, uid string, state int) error
+	UpdateDeviceProductionState(ctx context.Context, uid string, state ProductionState) error
+
+	// UpdateDevice updates the attributes of the given device uid which are set in the update
+	UpdateDevice(ctx context.Context, uid string, update DeviceUpdate) error
+
+	// MoveDevices moves the given device uids to the given device class, e.g. /Server/Linux
+	MoveDevices(ctx context.Context, uids []string, targetClass string) (*MoveResult, error)
+
+	// RenameDevice renames the device with the given uid returning the new uid of the device
+	RenameDevice(ctx context.Context, uid, newID string, retainGraphs bool) (string, error)
+
+	// ResetIP sets the manage IP of the given device uids to the given ip, or to the address the device name resolves to if empty
+	ResetIP(ctx context.Context, uids []string, ip string) error
+
+	// SetManageIP sets the manage IP of the given device uid
+	SetManageIP(ctx context.Context, uid, ip string) error
+
+	// RemodelDevice starts modeling the device with the given uid returning the job doing so
+	RemodelDevice(ctx context.Context, uid string) (*Job, error)
+
+	// WaitForModel waits for the modeling job of the given device uid to finish and reads when the device was last modeled
+	WaitForModel(ctx context.Context, uid string, job *Job) (*ModelResult, error)
+
+	// LockDevices locks the given device uids from updates and/or deletion
+	LockDevices(ctx context.Context, uids []string, opts LockOptions) error
+
+	// UnlockDevices removes all locks from the given device uids
+	UnlockDevices(ctx context.Context, uids []string) error
+
+	// SetProductionState sets the production state of all the given device uids
+	SetProductionState(ctx context.Context, uids []string, state ProductionState) error
+
+	// SetProductionStateByQuery sets the production state of all devices matching the query
+	SetProductionStateByQuery(ctx context.Context, query DeviceQuery, state ProductionState) error
+
+	// SetPriority sets the priority of all the given device uids
+	SetPriority(ctx context.Context, uids []string, priority int) error
+
+	// SetPriorityByQuery sets the priority of all devices matching the query
+	SetPriorityByQuery(ctx context.Context, query DeviceQuery, priority int) error
+
+	// ListOrganizers returns the tree of organizers below the given root, e.g. OrganizerGroups
+	ListOrganizers(ctx context.Context, root string) (*OrganizerNode, error)
+
+	// CreateOrganizer creates an organizer with the given name below the given parent path, e.g. /Systems/Netic
+	CreateOrganizer(ctx context.Context, parentPath, name, description string) (*OrganizerNode, error)
+
+	// EnsureOrganizerPath creates the organizer with the given path and any missing parents, e.g. /Systems/Netic/Customer
+	EnsureOrganizerPath(ctx context.Context, organizer string) error
+
+	// DeleteOrganizer deletes the organizer with the given path
+	DeleteOrganizer(ctx context.Context, organizer string) error
+
+	// MoveOrganizer moves the organizer with the given path below the target parent path returning its new uid
+	MoveOrganizer(ctx context.Context, organizer, targetParentPath string) (string, error)
+
+	// AddDevicesToOrganizer adds the given device uids to the group, system or location with the given path
+	AddDevicesToOrganizer(ctx context.Context, uids []string, organizer string) error
+
+	// RemoveDevicesFromOrganizer removes the given device uids from the group, system or location with the given path
+	RemoveDevicesFromOrganizer(ctx context.Context, uids []string, organizer string) error
+
+	// ProductionStates reads the production states configured in Zenoss
+	ProductionStates(ctx context.Context) (ProductionStateNames, error)
+
+	// GetJob returns the status of the Zenoss job with the given uuid
+	GetJob(ctx context.Context, uuid string) (*JobInfo, error)
+
+	// JobLog returns the log of the Zenoss job with the given uuid
+	JobLog(ctx context.Context, uuid string) ([]string, error)
+
+	// WaitForJob waits for the Zenoss job with the given uuid to finish returning *JobError if it did not succeed
+	WaitForJob(ctx context.Context, uuid string) (*JobInfo, error)
+
+	// AbortJobs aborts the Zenoss jobs with the given uuids
+	AbortJobs(ctx context.Context, uuids []string) error
 
 	// ReadCustomProperty reads the names custom property of the given device
 	ReadCustomProperty(ctx context.Context, uid string, id string) (*CustomProperty, error)
@@ -40,28 +136,61 @@ type Client interface {
 
 	// UpdateCustomProperty updates the value of the given customer property on the given device
 	UpdateCustomProperty(ctx context.Context, uid string, id string, value string) error
+
+	// Batch starts a batch of calls sent together in a single request per router
+	Batch(ctx context.Context) *Batch
+
+	// Call calls any method of a Zenoss router (Ext.Direct action, e.g. DeviceRouter) with data as its argument
+	// and decodes the result of the call into result
+	Call(ctx context.Context, router, method string, data interface{}, result interface{}) error
+
+	// CallPath calls any method of a Zenoss router like Call with the router served at the given path below /zport/dmd,
+	// e.g. evclasses_router, for routers where the path does not follow from the name of the router
+	CallPath(ctx context.Context, router, path, method string, data interface{}, result interface{}) error
 }
 
 type client struct {
-	client   *http.Client
-	url      string
-	tid      int
-	mTid     sync.Mutex
-	username string
-	password string
-	monitor  string
+	client  *http.Client
+	url     string
+	tid     int
+	mTid    sync.Mutex
+	auth    Authenticator
+	monitor string
+
+	maxResponseSize int64
+	retry           *RetryPolicy
+	limiter         *limiter
+	jobPollInterval time.Duration
+	jobTimeout      time.Duration
 }
 
 const (
 	actionDeviceRoute      action = "DeviceRouter"
 	actionPropertiesRouter action = "PropertiesRouter"
 	actionEventsRouter     action = "EventsRouter"
+	actionJobsRouter       action = "JobsRouter"
 
 	// DeviceRouter methods https://help.zenoss.com/dev/collection-zone-and-resource-manager-apis/codebase/routers/router-reference/devicerouter
 	methodGetDevices    method = "getDevices"
 	methodAddDevice     method = "addDevice"
 	methodRemoveDevices method = "removeDevices"
 	methodSetInfo       method = "setInfo"
+	methodGetInfo       method = "getInfo"
+	methodMoveDevices   method = "moveDevices"
+	methodRenameDevice  method = "renameDevice"
+	methodResetIP       method = "resetIp"
+	methodSetManageIP   method = "setManageIp"
+	methodLockDevices   method = "lockDevices"
+	methodRemodel       method = "remodel"
+	methodGetTree       method = "getTree"
+	methodAddNode       method = "addNode"
+	methodDeleteNode    method = "deleteNode"
+	methodMoveOrganizer method = "moveOrganizer"
+	methodObjectExists  method = "objectExists"
+
+	methodSetProductionState  method = "setProductionState"
+	methodSetPriority         method = "setPriority"
+	methodGetProductionStates method = "getProductionStates"
 
 	// PropertiesRouter methods https://help.zenoss.com/dev/collection-zone-and-resource-manager-apis/codebase/routers/router-reference/propertiesrouter
 	methodGetCustomProperties  method = "getCustomProperties" // Added method getCustomProperties to fetch custom properties
@@ -70,101 +199,117 @@ const (
 
 	methodAddEvent method = "add_event"
 
+	// JobsRouter methods https://help.zenoss.com/dev/collection-zone-and-resource-manager-apis/codebase/routers/router-reference/jobsrouter
+	methodJobDetail method = "detail"
+	methodJobAbort  method = "abort"
+
 	pathPropertiesRouter = "properties_router"
 	pathDeviceRouter     = "device_router"
 	pathEvconsoleRouter  = "evconsole_router"
+	pathJobsRouter       = "jobs_router"
+
+	// defaultMaxResponseSize is the maximum size of response bodies read from Zenoss unless configured otherwise
+	defaultMaxResponseSize = 64 << 20
 )
 
 // NewClient create new Zenoss instance
 func NewClient(baseURI, username, password, monitor string, insecureSkipTLSVerify bool) (Client, error) {
+	return New(baseURI,
+		WithBasicAuth(username, password),
+		WithMonitor(monitor),
+		WithInsecureSkipTLSVerify(insecureSkipTLSVerify),
+	)
+}
+
+// New create new Zenoss instance configured by the given options
+func New(baseURI string, opts ...Option) (Client, error) {
 	u, err := url.Parse(baseURI)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse given base URI: %w", err)
 	}
 	u.Path, _ = strings.CutSuffix(u.Path, "/zport/dmd")
 
-	return &client{
-		client: &http.Client{
-			Transport: &http.Transport{
-				//#nosec G402
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipTLSVerify},
-			},
-		},
-		url:      u.String(),
-		username: username,
-		password: password,
-		monitor:  monitor,
-		tid:      0,
-	}, nil
-}
-
-// AddEvent to component on device in Zenoss
-func (api *client) AddEvent(ctx context.Context, summary, message, device, component string, severity Severity, evClass, evKey string, extraData map[string]string) error {
-	if len(summary) > 255 {
-		return fmt.Errorf("'summary' must be less than 255")
-	}
-	if len(message) > 4095 {
-		return fmt.Errorf("'message' must be less than 4096 characters")
-	}
-	if len(component) > 255 {
-		return fmt.Errorf("'component' must be less than 255")
-	}
-	if len(evKey) > 127 {
-		return fmt.Errorf("'evKey' must be less than 127")
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
 	}
 
-	d := map[string]string{
-		"summary":    summary,
-		"message":    message,
-		"device":     device,
-		"component":  component,
-		"monitor":    api.monitor,
-		"severity":   string(severity),
-		"eventKey":   evKey,
-		"evclasskey": "",
-		"evclass":    evClass,
+	c := &client{
+		client:  cfg.buildHTTPClient(),
+		url:     u.String(),
+		auth:    cfg.auth,
+		monitor: cfg.monitor,
+		tid:     0,
+
+		maxResponseSize: cfg.maxResponseSize,
+		retry:           cfg.retry,
+		limiter:         newLimiter(cfg),
+		jobPollInterval: cfg.jobPollInterval,
+		jobTimeout:      cfg.jobTimeout,
 	}
-	for k, v := range extraData {
-		d[k] = v
+	if b, ok := c.auth.(binder); ok {
+		b.bind(c)
 	}
-	r := request{
-		Action: actionEventsRouter,
-		Method: methodAddEvent,
-		Data:   []interface{}{d},
+	return c, nil
+}
+
+// AddEvent to component on device in Zenoss
+func (api *client) AddEvent(ctx context.Context, summary, message, device, component string, severity Severity, evClass, evKey string, extraData map[string]string) error {
+	r, err := api.addEventRequest(summary, message, device, component, severity, evClass, evKey, extraData)
+	if err != nil {
+		return err
 	}
 	slog.Debug("Constructed request for Zenoss", "request", r)
 
 	var resp addEventResponse
-	err := api.doRequest(ctx, r, pathEvconsoleRouter, &resp)
+	err = api.doRequest(ctx, r, pathEvconsoleRouter, &resp)
 	if err != nil {
 		return fmt.Errorf("unable to create event: %w", err)
 	}
 
 	if !resp.Result.Success {
-		return fmt.Errorf("event could not be created: %+v", resp)
+		return fmt.Errorf("event could not be created: %w", resp.failure(resp.Result))
 	}
 
 	return nil
 }
 
 func (z *client) ReadDevice(ctx context.Context, uid string) (*Device, error) {
-	request := request{
-		Action: actionDeviceRoute,
-		Method: methodGetDevices,
-		Data: []interface{}{
-			deviceReadData{
-				UID: uid,
-			},
-		},
-	}
+	// The lock state is not part of the device list so it is read in the same batch
 	var dev deviceReadResponse
-	err := z.doRequest(ctx, request, pathDeviceRouter, &dev)
-	if err != nil {
-		return nil, fmt.Errorf("unable to read device: %w", err)
+	var info deviceInfoResponse
+	errs, _ := z.Batch(ctx).
+		add(pathDeviceRouter, request{
+			Action: actionDeviceRoute,
+			Method: methodGetDevices,
+			Data: []interface{}{
+				deviceReadData{
+					UID: uid,
+				},
+			},
+		}, &dev, nil, func() error { return nil }).
+		add(pathDeviceRouter, request{
+			Action: actionDeviceRoute,
+			Method: methodGetInfo,
+			Data: []interface{}{
+				deviceInfoData{
+					UID:  uid,
+					Keys: []string{"locking"},
+				},
+			},
+		}, &info, nil, func() error {
+			if !info.Result.Success {
+				return fmt.Errorf("error reading device info: %w", info.failure(info.Result.result))
+			}
+			return nil
+		}).
+		Do()
+	if errs[0] != nil {
+		return nil, fmt.Errorf("unable to read device: %w", errs[0])
 	}
 
 	if dev.Result.Count > 1 {
-		return nil, fmt.Errorf("error multiple devices returned")
+		return nil, fmt.Errorf("error reading device: %w", ErrMultipleResults)
 	}
 
 	if dev.Result.Count == 0 || len(dev.Result.Devices) == 0 {
@@ -172,131 +317,78 @@ func (z *client) ReadDevice(ctx context.Context, uid string) (*Device, error) {
 	}
 
 	if !dev.Result.Success {
-		return nil, fmt.Errorf("error reading device")
+		return nil, fmt.Errorf("error reading device: %w", dev.failure(dev.Result.result))
 	}
 
-	return &dev.Result.Devices[0], nil
+	// The device is returned without its lock state if only that could not be read
+	device := &dev.Result.Devices[0]
+	if errs[1] != nil {
+		slog.Debug("Unable to read lock state of device", "uid", uid, "error", errs[1])
+		return device, nil
+	}
+	device.Locking = info.Result.Data.Locking
+	return device, nil
 }
 
 func (z *client) CreateDevice(ctx context.Context, dev NewDevice) (*Device, error) {
-	req := request{
-		Action: actionDeviceRoute,
-		Method: methodAddDevice,
-		Data: []interface{}{
-			dev,
-		},
-	}
-	var res deviceAddResponse
-	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
+	job, err := z.CreateDeviceAsync(ctx, dev)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create device: %w", err)
+		return nil, err
 	}
 
-	if !res.Result.Success {
-		return nil, fmt.Errorf("add device returned unsuccessfull")
+	_, err = job.Wait(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create device %s: %w", dev.Name, err)
 	}
 
-	req = request{
-		Action: actionDeviceRoute,
-		Method: methodGetDevices,
-		Data: []interface{}{
-			deviceReadData{
-				UID: "",
-				Params: &deviceReadParams{
-					Name: dev.Name,
-				},
-			},
-		},
+	found, err := z.devicesNamed(ctx, dev.Name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read device after creation: %w", err)
 	}
 
-	var read deviceReadResponse
-	r := 0
-	for {
-		read = deviceReadResponse{}
-		err = z.doRequest(ctx, req, pathDeviceRouter, &read)
-		if err != nil {
-			return nil, fmt.Errorf("unable to read device after creation: %w", err)
-		}
-		r++
-
-		if !res.Result.Success {
-			return nil, fmt.Errorf("read of added device returned unsuccessfull")
-		}
-
-		if read.Result.Count == 0 || len(read.Result.Devices) == 0 {
-			if r > 5 { // 5* waiting 2 sec = 10 sec
-				return nil, fmt.Errorf("no devices returned after creation of %s", dev.Name)
-			}
-		} else {
-			break
-		}
-
-		select {
-		case <-ctx.Done(): // Return if context is cancelled
-			return nil, ctx.Err()
-		case <-time.After(2 * time.Second): // Proceed after timeout
-		}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no devices returned after creation of %s: %w", dev.Name, ErrNotFound)
 	}
 
-	if read.Result.Count > 1 || len(read.Result.Devices) > 1 {
-		return nil, fmt.Errorf("multiple devices returned after creation of %s", dev.Name)
+	if len(found) > 1 {
+		return nil, fmt.Errorf("%w after creation of %s", ErrMultipleResults, dev.Name)
 	}
 
-	return &read.Result.Devices[0], nil
+	return &found[0], nil
 }
 
-func (z *client) DeleteDevice(ctx context.Context, uid string) error {
+func (z *client) CreateDeviceAsync(ctx context.Context, dev NewDevice) (*Job, error) {
 	req := request{
 		Action: actionDeviceRoute,
-		Method: methodGetDevices,
+		Method: methodAddDevice,
 		Data: []interface{}{
-			deviceReadData{
-				UID: uid,
-			},
+			dev,
 		},
 	}
-	var dev deviceReadResponse
-	err := z.doRequest(ctx, req, pathDeviceRouter, &dev)
+	var res newJobsResponse
+	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
 	if err != nil {
-		return fmt.Errorf("unable to read hash for deleting device: %w", err)
+		return nil, fmt.Errorf("unable to create device: %w", err)
 	}
 
-	req = request{
-		Action: actionDeviceRoute,
-		Method: methodRemoveDevices,
-		Data: []interface{}{
-			deviceRemoveData{
-				Action:       "delete",
-				UIDs:         []string{uid},
-				Hashcheck:    dev.Result.Hash,
-				DeleteEvents: true,
-			},
-		},
-	}
-	var res deviceRemoveResponse
-	err = z.doRequest(ctx, req, pathDeviceRouter, &res)
-	if err != nil {
-		return fmt.Errorf("unable to delete device: %w", err)
+	if !res.Result.Success {
+		return nil, fmt.Errorf("add device returned unsuccessful: %w", res.failure(res.Result.result))
 	}
 
-	if !res.Result.Success {
-		return fmt.Errorf("remove device returned unsuccessful")
+	job := z.newJob(res.Result.Jobs)
+	if job == nil {
+		return nil, fmt.Errorf("add device of %s returned no job", dev.Name)
 	}
+	return job, nil
+}
 
-	return nil
+func (z *client) DeleteDevice(ctx context.Context, uid string) error {
+	// Performance data is handled according to the default of the Zenoss version
+	return z.removeDevices(ctx, []string{uid}, DeleteModeDelete, "", true, nil)
 }
 
-func (z *client) UpdateDeviceProductionState(ctx context.Context, uid string, state int) error {
-	req := request{
-		Action: actionDeviceRoute,
-		Method: methodSetInfo,
-		Data: []interface{}{
-			deviceSetInfoData{
-				UID:             uid,
-				ProductionState: state,
-			},
-		},
-	}
+func (z *client) UpdateDeviceProductionState(ctx context.Context, uid string, state ProductionState) error {
+	req := deviceProductionStateRequest(uid, state)
 	var res deviceSetInfoResponse
 	err := z.doRequest(ctx, req, pathDeviceRouter, &res)
 	if err != nil {
@@ -304,7 +396,7 @@ func (z *client) UpdateDeviceProductionState(ctx context.Context, uid string, st
 	}
 
 	if !res.Result.Success {
-		return fmt.Errorf("update device returned unsuccessful")
+		return fmt.Errorf("update device returned unsuccessful: %w", res.failure(res.Result))
 	}
 
 	return nil
@@ -329,8 +421,12 @@ func (z *client) ReadCustomProperty(ctx context.Context, uid string, id string)
 		return nil, fmt.Errorf("unable to read custom properties from device: %w", err)
 	}
 
-	if !readResponse.Result.Success || readResponse.Result.Count > 1 {
-		return nil, fmt.Errorf("error reading custom properties from device or more devices returned")
+	if !readResponse.Result.Success {
+		return nil, fmt.Errorf("error reading custom properties from device: %w", readResponse.failure(readResponse.Result.result))
+	}
+
+	if readResponse.Result.Count > 1 {
+		return nil, fmt.Errorf("error reading custom properties from device: %w", ErrMultipleResults)
 	}
 
 	if readResponse.Result.Count == 0 || len(readResponse.Result.Data) == 0 {
@@ -376,14 +472,79 @@ func (z *client) DeleteCustomProperty(ctx context.Context, uid string, id string
 	}
 
 	if !res.Result.Success {
-		return fmt.Errorf("remove custom property returned unsuccessful")
+		return fmt.Errorf("remove custom property returned unsuccessful: %w", res.failure(res.Result))
 	}
 
 	return nil
 }
 
 func (z *client) UpdateCustomProperty(ctx context.Context, uid string, id string, value string) error {
-	req := request{
+	req := customPropertyUpdateRequest(uid, id, value)
+	var res customPropertyUpdateResponse
+	err := z.doRequest(ctx, req, pathPropertiesRouter, &res)
+	if err != nil {
+		return fmt.Errorf("unable to update custom property: %w", err)
+	}
+
+	if !res.Result.Success {
+		return fmt.Errorf("update custom property returned unsuccessful: %w", res.failure(res.Result))
+	}
+
+	return nil
+}
+
+func (api *client) addEventRequest(summary, message, device, component string, severity Severity, evClass, evKey string, extraData map[string]string) (request, error) {
+	if len(summary) > 255 {
+		return request{}, fmt.Errorf("'summary' must be less than 255")
+	}
+	if len(message) > 4095 {
+		return request{}, fmt.Errorf("'message' must be less than 4096 characters")
+	}
+	if len(component) > 255 {
+		return request{}, fmt.Errorf("'component' must be less than 255")
+	}
+	if len(evKey) > 127 {
+		return request{}, fmt.Errorf("'evKey' must be less than 127")
+	}
+
+	d := map[string]string{
+		"summary":    summary,
+		"message":    message,
+		"device":     device,
+		"component":  component,
+		"monitor":    api.monitor,
+		"severity":   string(severity),
+		"eventKey":   evKey,
+		"evclasskey": "",
+		"evclass":    evClass,
+	}
+	for k, v := range extraData {
+		d[k] = v
+	}
+	return request{
+		Action: actionEventsRouter,
+		Method: methodAddEvent,
+		Data:   []interface{}{d},
+	}, nil
+}
+
+func deviceProductionStateRequest(uid string, state ProductionState) request {
+	return request{
+		Action: actionDeviceRoute,
+		Method: methodSetInfo,
+		Data: []interface{}{
+			deviceSetInfoData{
+				UID: uid,
+				DeviceUpdate: DeviceUpdate{
+					ProductionState: &state,
+				},
+			},
+		},
+	}
+}
+
+func customPropertyUpdateRequest(uid string, id string, value string) request {
+	return request{
 		Action: actionPropertiesRouter,
 		Method: methodUpdateCustomProperty,
 		Data: []interface{}{
@@ -394,43 +555,162 @@ func (z *client) UpdateCustomProperty(ctx context.Context, uid string, id string
 			},
 		},
 	}
-	var res customPropertyUpdateResponse
-	err := z.doRequest(ctx, req, pathPropertiesRouter, &res)
+}
+
+func (z *client) doRequest(ctx context.Context, request request, routerPath string, target interface{}) error {
+	request.Tid = z.nextTid()
+	status, body, err := z.send(ctx, routerPath, request, idempotentMethods[request.Method])
 	if err != nil {
-		return fmt.Errorf("unable to update custom property: %w", err)
+		return err
 	}
+	return decodeResponse(status, body, target)
+}
 
-	if !res.Result.Success {
-		return fmt.Errorf("update custom property returned unsuccessful")
+// send posts the payload to the given router and returns the status code and body of a successful JSON response.
+// Failed attempts are retried according to the retry policy, writes only if the policy allows it.
+func (z *client) send(ctx context.Context, routerPath string, payload interface{}, idempotent bool) (int, []byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, err
 	}
 
-	return nil
+	attempts := 1
+	if z.retry != nil && (idempotent || z.retry.RetryWrites) {
+		attempts = z.retry.MaxAttempts
+	}
+	renewed := false
+	for attempt := 1; ; attempt++ {
+		status, body, err := z.post(ctx, routerPath, data)
+		if !renewed && isSessionExpired(err) {
+			// Repeat the attempt once with a new session
+			renewed = true
+			attempt--
+			continue
+		}
+		if err == nil || attempt >= attempts || ctx.Err() != nil || !z.retry.retryable(err) {
+			return status, body, err
+		}
+
+		delay := z.retry.delay(attempt, err)
+		slog.Debug("Retrying call to Zenoss", "path", routerPath, "attempt", attempt, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
 }
 
-func (z *client) doRequest(ctx context.Context, request request, routerPath string, target interface{}) error {
-	request.Tid = z.nextTid()
-	data, err := json.Marshal(request)
+// post makes a single attempt of posting data to the given router
+func (z *client) post(ctx context.Context, routerPath string, data []byte) (int, []byte, error) {
+	release, err := z.limiter.acquire(ctx)
 	if err != nil {
-		return err
+		return 0, nil, err
 	}
+	defer release()
+
 	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/zport/dmd/%s", z.url, routerPath), bytes.NewBuffer(data))
 	if err != nil {
-		return fmt.Errorf("unable to create request for Zenoss: %w", err)
+		return 0, nil, fmt.Errorf("unable to create request for Zenoss: %w", err)
 	}
-	req.SetBasicAuth(z.username, z.password)
 	req.Header.Set("Content-Type", "application/json")
+	if z.auth != nil {
+		if err := z.auth.Authenticate(req); err != nil {
+			return 0, nil, fmt.Errorf("unable to authenticate request for Zenoss: %w", err)
+		}
+	}
 
 	res, err := z.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("error calling Zenoss: %s", err)
+		return 0, nil, fmt.Errorf("error calling Zenoss: %w", err)
 	}
 	defer res.Body.Close()
 
-	buf := &strings.Builder{}
-	tee := io.TeeReader(res.Body, buf)
-	err = json.NewDecoder(tee).Decode(target)
+	limit := z.maxResponseSize
+	if limit <= 0 {
+		limit = defaultMaxResponseSize
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, limit+1))
+	if err != nil {
+		return 0, nil, fmt.Errorf("unable to read response from Zenoss: %w", err)
+	}
+	if int64(len(body)) > limit {
+		return 0, nil, &HTTPError{
+			StatusCode:  res.StatusCode,
+			ContentType: res.Header.Get("Content-Type"),
+			Body:        excerpt(body),
+			Err:         ErrResponseTooLarge,
+		}
+	}
+
+	if err := checkResponse(res, body); err != nil {
+		if e, ok := z.auth.(sessionExpirer); ok && isLoginPage(res) {
+			e.expire(req)
+			err.(*HTTPError).sessionExpired = true
+		}
+		return 0, nil, err
+	}
+	return res.StatusCode, body, nil
+}
+
+// checkResponse verifies the response is a successful JSON response and not e.g. an error or login page
+func checkResponse(res *http.Response, body []byte) error {
+	contentType := res.Header.Get("Content-Type")
+	httpErr := &HTTPError{
+		StatusCode:  res.StatusCode,
+		ContentType: contentType,
+		Body:        excerpt(body),
+		retryAfter:  parseRetryAfter(res.Header.Get("Retry-After")),
+	}
+
+	switch {
+	case isLoginPage(res):
+		httpErr.Err = ErrUnauthorized
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		httpErr.Err = classify(res.StatusCode, "")
+	case !isJSONContentType(contentType):
+		httpErr.Err = ErrUnexpectedContentType
+	default:
+		return nil
+	}
+	return httpErr
+}
+
+// isLoginPage reports whether the request was redirected to the Zenoss login form
+func isLoginPage(res *http.Response) bool {
+	return res.Request != nil && res.Request.URL != nil && strings.Contains(res.Request.URL.Path, "/login_form")
+}
+
+// isJSONContentType reports whether the content type may carry JSON. Missing and plain text content types
+// are accepted as not all Zenoss versions and proxies label the Ext.Direct responses correctly.
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
 	if err != nil {
-		return fmt.Errorf("unable to parse response from Zenoss: %w - response: %s", err, buf.String())
+		return false
+	}
+	switch mediaType {
+	case "application/json", "text/javascript", "application/javascript", "text/plain":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json")
+}
+
+// decodeResponse decodes a router response into target and returns the error of an Ext.Direct exception
+func decodeResponse(status int, body []byte, target interface{}) error {
+	err := json.Unmarshal(body, target)
+	if err != nil {
+		return fmt.Errorf("unable to parse response from Zenoss: %w - response: %s", err, excerpt(body))
+	}
+
+	if e, ok := target.(enveloped); ok {
+		env := e.envelope()
+		env.status = status
+		if err := env.exception(); err != nil {
+			return err
+		}
 	}
 
 	return nil
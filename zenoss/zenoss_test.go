@@ -3,12 +3,14 @@ package zenoss
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"log/slog"
 
@@ -72,9 +74,9 @@ func TestReadDevice(t *testing.T) {
 		if req.URL.Path == "/zport/dmd/device_router" {
 			buf := new(strings.Builder)
 			io.Copy(buf, req.Body)
-			assert.Equal(t, `{"action":"DeviceRouter","method":"getDevices","data":[{"uid":"/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk"}],"tid":1}`, buf.String())
 			assert.Equal(t, "POST", req.Method)
-			rw.Write([]byte(readDeviceResponse))
+			assert.Equal(t, `[{"action":"DeviceRouter","method":"getDevices","data":[{"uid":"/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk"}],"tid":1},{"action":"DeviceRouter","method":"getInfo","data":[{"uid":"/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk","keys":["locking"]}],"tid":2}]`, buf.String())
+			rw.Write(batchResponse(t, buf.String(), readDeviceResponse, readDeviceLockingResponse))
 		}
 	}))
 	defer server.Close()
@@ -82,6 +84,7 @@ func TestReadDevice(t *testing.T) {
 	device, err := api.ReadDevice(context.Background(), "/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk")
 	assert.NoError(t, err)
 	assert.Equal(t, "oaas1.k8s.jysk.netic.dk", device.Name)
+	assert.Equal(t, &Locking{Deletion: true, SendEvent: true}, device.Locking)
 }
 
 func TestReadDeviceNotFound(t *testing.T) {
@@ -89,9 +92,9 @@ func TestReadDeviceNotFound(t *testing.T) {
 		if req.URL.Path == "/zport/dmd/device_router" {
 			buf := new(strings.Builder)
 			io.Copy(buf, req.Body)
-			assert.Equal(t, `{"action":"DeviceRouter","method":"getDevices","data":[{"uid":"/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk"}],"tid":1}`, buf.String())
 			assert.Equal(t, "POST", req.Method)
-			rw.Write([]byte(readDeviceResponseNotFound))
+			assert.Equal(t, `[{"action":"DeviceRouter","method":"getDevices","data":[{"uid":"/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk"}],"tid":1},{"action":"DeviceRouter","method":"getInfo","data":[{"uid":"/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk","keys":["locking"]}],"tid":2}]`, buf.String())
+			rw.Write(batchResponse(t, buf.String(), readDeviceResponseNotFound, `{"action": "DeviceRouter", "result": {"msg": "ObjectNotFound: Cannot find \"/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk\"", "success": false}, "type": "rpc", "method": "getInfo"}`))
 		}
 	}))
 	defer server.Close()
@@ -101,25 +104,57 @@ func TestReadDeviceNotFound(t *testing.T) {
 	assert.Nil(t, device)
 }
 
+func TestReadDeviceLockStateFailed(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		rw.Write(batchResponse(t, buf.String(), readDeviceResponse, `{"action": "DeviceRouter", "result": {"msg": "Permission denied", "success": false}, "type": "rpc", "method": "getInfo"}`))
+	}))
+	defer server.Close()
+
+	device, err := api.ReadDevice(context.Background(), "/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk")
+	assert.NoError(t, err)
+	if assert.NotNil(t, device) {
+		assert.Equal(t, "oaas1.k8s.jysk.netic.dk", device.Name)
+		assert.Nil(t, device.Locking)
+	}
+}
+
 func TestCreateDevice(t *testing.T) {
+	polls := map[string]int{}
 	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		assert.Equal(t, "POST", req.Method)
 
 		buf := new(strings.Builder)
 		io.Copy(buf, req.Body)
-		if strings.Contains(buf.String(), "addDevice") {
+		var r struct {
+			Method string `json:"method"`
+			Data   []struct {
+				JobID string `json:"jobid"`
+			} `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(buf.String()), &r))
+		switch r.Method {
+		case "addDevice":
 			assert.Equal(t, `{"action":"DeviceRouter","method":"addDevice","data":[{"deviceName":"oaas1.k8s.jysk.netic.dk","deviceClass":"/VirtualDevices/shared-kubernetes","collector":"localhost","model":true,"productionState":500,"groupPaths":["/SLA/Plus/Ping_Only"],"systemPaths":["/Netic/Test"],"locationPath":"/Netic/DC4"}],"tid":1}`, buf.String())
 			rw.Write([]byte(addDeviceResponse))
-		} else if strings.Contains(buf.String(), "\"tid\":2") {
-			assert.Equal(t, `{"action":"DeviceRouter","method":"getDevices","data":[{"params":{"name":"oaas1.k8s.jysk.netic.dk"}}],"tid":2}`, buf.String())
-			rw.Write([]byte(readDeviceResponseEmpty))
-		} else {
-			assert.Equal(t, `{"action":"DeviceRouter","method":"getDevices","data":[{"params":{"name":"oaas1.k8s.jysk.netic.dk"}}],"tid":3}`, buf.String())
+		case "getInfo":
+			assert.Equal(t, "/zport/dmd/jobs_router", req.URL.Path)
+			polls[r.Data[0].JobID]++
+			status := "SUCCESS"
+			if r.Data[0].JobID == "fe2a1578-0d89-4370-ab0d-d4cc86d1ca73" && polls[r.Data[0].JobID] == 1 {
+				status = "STARTED"
+			}
+			fmt.Fprintf(rw, `{"uuid": "1", "action": "JobsRouter", "result": {"data": {"uuid": %q, "status": %q}, "success": true}, "tid": 1, "type": "rpc", "method": "getInfo"}`, r.Data[0].JobID, status)
+		case "detail":
+			rw.Write([]byte(`{"uuid": "1", "action": "JobsRouter", "result": {"content": ["Job completed"], "logfile": "/opt/zenoss/log/jobs/job.log"}, "tid": 1, "type": "rpc", "method": "detail"}`))
+		default:
+			assert.Equal(t, `{"action":"DeviceRouter","method":"getDevices","data":[{"params":{"name":"oaas1.k8s.jysk.netic.dk"},"limit":100}],"tid":7}`, buf.String())
 			rw.Write([]byte(readDeviceResponse))
 		}
-
 	}))
 	defer server.Close()
+	api.(*client).jobPollInterval = time.Millisecond
 
 	dev := NewDevice{
 		Name:            "oaas1.k8s.jysk.netic.dk",
@@ -135,6 +170,7 @@ func TestCreateDevice(t *testing.T) {
 	device, err := api.CreateDevice(context.Background(), dev)
 	assert.NoError(t, err)
 	assert.Equal(t, "oaas1.k8s.jysk.netic.dk", device.Name)
+	assert.Equal(t, map[string]int{"c3ab77cf-fdea-4daa-8c3e-7131049439d1": 1, "fe2a1578-0d89-4370-ab0d-d4cc86d1ca73": 2}, polls)
 }
 
 func TestDeleteDevice(t *testing.T) {
@@ -158,6 +194,26 @@ func TestDeleteDevice(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestDeleteDeviceLocked(t *testing.T) {
+	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		buf := new(strings.Builder)
+		io.Copy(buf, req.Body)
+		switch {
+		case strings.Contains(buf.String(), "removeDevices"):
+			rw.Write([]byte(`{"uuid": "2", "action": "DeviceRouter", "result": {"msg": "Failed to remove devices.", "success": false}, "tid": 2, "type": "rpc", "method": "removeDevices"}`))
+		case strings.Contains(buf.String(), "getInfo"):
+			assert.Equal(t, `{"action":"DeviceRouter","method":"getInfo","data":[{"uid":"/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk","keys":["locking"]}],"tid":3}`, buf.String())
+			rw.Write([]byte(`{"uuid": "3", "action": "DeviceRouter", "result": {"data": {"locking": {"updates": true, "deletion": true, "events": false}}, "success": true}, "tid": 3, "type": "rpc", "method": "getInfo"}`))
+		default:
+			rw.Write([]byte(readDeviceResponse))
+		}
+	}))
+	defer server.Close()
+
+	err := api.DeleteDevice(context.Background(), "/zport/dmd/Devices/VirtualDevices/jysk-k8s/devices/oaas1.k8s.jysk.netic.dk")
+	assert.ErrorIs(t, err, ErrLocked)
+}
+
 func TestUpdateDeviceProductionState(t *testing.T) {
 	api, server := newStubAPI(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 		buf := new(strings.Builder)
@@ -232,6 +288,21 @@ func newStubAPI(handler http.HandlerFunc) (Client, *httptest.Server) {
 	return api, server
 }
 
+// batchResponse returns the responses as the response to the batch in body using the tids of its requests
+func batchResponse(t *testing.T, body string, responses ...string) []byte {
+	var reqs []request
+	assert.NoError(t, json.Unmarshal([]byte(body), &reqs))
+	assert.Len(t, reqs, len(responses))
+	batch := make([]map[string]interface{}, len(reqs))
+	for i := range reqs {
+		assert.NoError(t, json.Unmarshal([]byte(responses[i]), &batch[i]))
+		batch[i]["tid"] = reqs[i].Tid
+	}
+	res, err := json.Marshal(batch)
+	assert.NoError(t, err)
+	return res
+}
+
 func init() {
 	opts := &slog.HandlerOptions{
 		Level:     slog.LevelDebug,
@@ -324,6 +395,24 @@ const readDeviceResponse = `{
 	"method": "getDevices"
   }`
 
+const readDeviceLockingResponse = `{
+	"uuid": "2",
+	"action": "DeviceRouter",
+	"result": {
+	  "data": {
+		"locking": {
+		  "updates": false,
+		  "deletion": true,
+		  "events": true
+		}
+	  },
+	  "success": true
+	},
+	"tid": 2,
+	"type": "rpc",
+	"method": "getInfo"
+  }`
+
 const readDeviceResponseNotFound = `{
 	"uuid": "f353184f-59f4-4057-9cc6-8614dd7cc91e",
 	"action": "DeviceRouter",